@@ -9,6 +9,9 @@ import (
 	"github.com/aatuh/api-toolkit/docs"
 	"github.com/aatuh/api-toolkit/health"
 	recoverx "github.com/aatuh/api-toolkit/httpx/recover"
+	"github.com/aatuh/api-toolkit/i18n"
+	"github.com/aatuh/api-toolkit/loglevel"
+	"github.com/aatuh/api-toolkit/middleware/compress"
 	"github.com/aatuh/api-toolkit/middleware/cors"
 	jsonmw "github.com/aatuh/api-toolkit/middleware/json"
 	maxbody "github.com/aatuh/api-toolkit/middleware/maxbody"
@@ -22,15 +25,51 @@ import (
 	"github.com/aatuh/api-toolkit/specs"
 )
 
+// RouterOption adjusts the default middleware stack.
+type RouterOption func(*routerOptions)
+
+type routerOptions struct {
+	timeoutRoutes map[string]time.Duration
+	timeoutExempt func(r *http.Request) bool
+}
+
+// WithTimeoutRoutes sets per-path-prefix request timeouts, replacing the
+// 5s default on matching paths; a negative duration disables the
+// timeout. See timeout.Routes. Exempt long-lived streams such as SSE
+// endpoints this way:
+//
+//	bootstrap.NewDefaultRouter(log, bootstrap.WithTimeoutRoutes(map[string]time.Duration{
+//		"/events": -1,
+//	}))
+func WithTimeoutRoutes(budgets map[string]time.Duration) RouterOption {
+	return func(o *routerOptions) { o.timeoutRoutes = budgets }
+}
+
+// WithTimeoutExempt serves requests matched by exempt without a timeout.
+// Match on the route, not on headers the client controls.
+func WithTimeoutExempt(exempt func(r *http.Request) bool) RouterOption {
+	return func(o *routerOptions) { o.timeoutExempt = exempt }
+}
+
 // NewDefaultRouter constructs a router with a sensible default middleware stack.
-func NewDefaultRouter(log ports.Logger) ports.HTTPRouter {
+func NewDefaultRouter(log ports.Logger, opts ...RouterOption) ports.HTTPRouter {
+	var o routerOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
 	var r ports.HTTPRouter = chi.New()
 	var mw ports.HTTPMiddleware = chi.NewMiddleware()
 
 	// Core middlewares
 	r.Use(mw.RequestID())
 	r.Use(mw.RealIP())
+	// Trace and requestlog wrap recovery so panics are logged with the
+	// same correlation IDs that the 500 Problem reports.
+	r.Use(tracemw.Middleware(tracemw.Options{TrustIncoming: false}))
+	r.Use(requestlog.New(log).Handler)
 	r.Use(recoverx.Middleware())
+	r.Use(i18n.Middleware(nil))
+	r.Use(compress.New(compress.Options{}).Handler)
 
 	// Standard middlewares
 	corsh := cors.New()
@@ -39,22 +78,47 @@ func NewDefaultRouter(log ports.Logger) ports.HTTPRouter {
 	r.Use(rateln.New(rateln.Options{Capacity: 30, RefillRate: 15}).Handler)
 	r.Use(maxbody.New(1 << 20).Handler)
 	r.Use(jsonmw.New(true).Handler)
-	r.Use(timeoutmw.New(5 * time.Second).Handler)
-	r.Use(requestlog.New(log).Handler)
+	tm := timeoutmw.New(5 * time.Second)
+	tm.Exempt = o.timeoutExempt
+	if o.timeoutRoutes != nil {
+		tm.Budget = timeoutmw.Routes(o.timeoutRoutes)
+	}
+	r.Use(tm.Handler)
 	r.Use(metricsmw.New(metricsmw.NewPrometheusRecorder(nil, nil)).Handler)
-	r.Use(tracemw.Middleware(tracemw.Options{TrustIncoming: false}))
 
 	return r
 }
 
-// MountSystemEndpoints registers health, docs, and metrics endpoints.
-func MountSystemEndpoints(r ports.HTTPRouter, hm *health.Handler, dm *docs.Handler) {
+// SystemOption enables optional system endpoints.
+type SystemOption func(*systemOptions)
+
+type systemOptions struct {
+	logLevel ports.LogLevelController
+}
+
+// WithLogLevel mounts the runtime log level endpoint (specs.LogLevel) backed
+// by ctrl, e.g. a logger from logzap. Only use it on routers that are not
+// publicly reachable or that sit behind authentication.
+func WithLogLevel(ctrl ports.LogLevelController) SystemOption {
+	return func(o *systemOptions) { o.logLevel = ctrl }
+}
+
+// MountSystemEndpoints registers health, docs, and metrics endpoints, plus
+// any optional endpoints enabled through opts.
+func MountSystemEndpoints(r ports.HTTPRouter, hm *health.Handler, dm *docs.Handler, opts ...SystemOption) {
+	var o systemOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
 	hm.RegisterRoutes(r)
 	dm.RegisterRoutes(r)
 	r.Get(specs.Metrics, func(w http.ResponseWriter, r *http.Request) {
 		h := metricsmw.PrometheusHandler()
 		h.ServeHTTP(w, r)
 	})
+	if o.logLevel != nil {
+		loglevel.NewHandler(o.logLevel).RegisterRoutes(r)
+	}
 }
 
 // StartServer runs an HTTP server and performs graceful shutdown when the
@@ -1,9 +1,11 @@
 package chi
 
 import (
+	"context"
 	"net/http"
 
 	"github.com/aatuh/api-toolkit/ports"
+	"github.com/aatuh/api-toolkit/realip"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 )
@@ -36,9 +38,11 @@ func (m *Middleware) RequestID() func(http.Handler) http.Handler {
 	return middleware.RequestID
 }
 
-// RealIP returns the real IP middleware.
+// RealIP returns realip.Default().Handler, which only believes
+// X-Forwarded-For from loopback and private-range proxies. Use
+// realip.New for other proxy ranges.
 func (m *Middleware) RealIP() func(http.Handler) http.Handler {
-	return middleware.RealIP
+	return realip.Default().Handler
 }
 
 // Recoverer returns the recoverer middleware.
@@ -63,3 +67,18 @@ func (u *URLParamExtractor) URLParam(r *http.Request, key string) string {
 func URLParam(r *http.Request, key string) string {
 	return chi.URLParam(r, key)
 }
+
+// GetRequestID returns the request ID assigned by the RequestID middleware.
+// It falls back to the incoming X-Request-ID header when the middleware has
+// not run.
+func GetRequestID(r *http.Request) string {
+	if id := RequestIDFromContext(r.Context()); id != "" {
+		return id
+	}
+	return r.Header.Get(middleware.RequestIDHeader)
+}
+
+// RequestIDFromContext returns the request ID stored in ctx, if any.
+func RequestIDFromContext(ctx context.Context) string {
+	return middleware.GetReqID(ctx)
+}
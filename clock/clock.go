@@ -1,6 +1,7 @@
 package clock
 
 import (
+	"context"
 	"time"
 
 	"github.com/aatuh/api-toolkit/ports"
@@ -9,9 +10,63 @@ import (
 // SystemClock implements shared.Clock using time.Now().
 type SystemClock struct{}
 
-func (SystemClock) Now() time.Time { return time.Now().UTC() }
+func (SystemClock) Now() time.Time                         { return time.Now().UTC() }
+func (SystemClock) Since(t time.Time) time.Duration        { return time.Since(t) }
+func (SystemClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+func (SystemClock) NewTimer(d time.Duration) ports.Timer {
+	return systemTimer{t: time.NewTimer(d)}
+}
+
+func (SystemClock) NewTicker(d time.Duration) ports.Ticker {
+	return systemTicker{t: time.NewTicker(d)}
+}
 
 // NewSystemClock creates a new system clock that implements ports.Clock.
 func NewSystemClock() ports.Clock {
 	return &SystemClock{}
 }
+
+// OrSystem returns c, or the system clock when c is nil.
+func OrSystem(c ports.Clock) ports.Clock {
+	if c == nil {
+		return NewSystemClock()
+	}
+	return c
+}
+
+// WithTimeout is context.WithTimeout driven by c. The system clock gets a
+// real deadline; other clocks cancel the context when their timer fires,
+// in which case ctx.Deadline reports none and context.Cause returns
+// context.DeadlineExceeded.
+func WithTimeout(ctx context.Context, c ports.Clock, d time.Duration) (context.Context, context.CancelFunc) {
+	switch c.(type) {
+	case nil, SystemClock, *SystemClock:
+		return context.WithTimeout(ctx, d)
+	}
+	ctx, cancel := context.WithCancelCause(ctx)
+	t := c.NewTimer(d)
+	go func() {
+		select {
+		case <-t.C():
+			cancel(context.DeadlineExceeded)
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, func() {
+		t.Stop()
+		cancel(context.Canceled)
+	}
+}
+
+type systemTimer struct{ t *time.Timer }
+
+func (s systemTimer) C() <-chan time.Time        { return s.t.C }
+func (s systemTimer) Stop() bool                 { return s.t.Stop() }
+func (s systemTimer) Reset(d time.Duration) bool { return s.t.Reset(d) }
+
+type systemTicker struct{ t *time.Ticker }
+
+func (s systemTicker) C() <-chan time.Time   { return s.t.C }
+func (s systemTicker) Stop()                 { s.t.Stop() }
+func (s systemTicker) Reset(d time.Duration) { s.t.Reset(d) }
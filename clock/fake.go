@@ -0,0 +1,209 @@
+package clock
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/aatuh/api-toolkit/ports"
+)
+
+// Fake is a manually driven ports.Clock for tests. Time only moves on
+// Advance or Set, which fire any timers and tickers that became due.
+type Fake struct {
+	mu      sync.Mutex
+	now     time.Time
+	waiters []*fakeWaiter
+	changed chan struct{}
+}
+
+// NewFake returns a fake clock set to t.
+func NewFake(t time.Time) *Fake {
+	return &Fake{now: t, changed: make(chan struct{})}
+}
+
+// Now returns the fake time.
+func (f *Fake) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+// Since returns the fake time elapsed since t.
+func (f *Fake) Since(t time.Time) time.Duration { return f.Now().Sub(t) }
+
+// After returns a channel that receives once the clock has advanced by d.
+func (f *Fake) After(d time.Duration) <-chan time.Time { return f.NewTimer(d).C() }
+
+// NewTimer returns a timer that fires once the clock has advanced by d.
+func (f *Fake) NewTimer(d time.Duration) ports.Timer {
+	w := &fakeWaiter{clock: f, ch: make(chan time.Time, 1)}
+	f.mu.Lock()
+	f.schedule(w, f.now.Add(d))
+	f.setLocked(f.now) // fire immediately when d <= 0
+	f.mu.Unlock()
+	return &fakeTimer{w}
+}
+
+// NewTicker returns a ticker firing every d of fake time.
+func (f *Fake) NewTicker(d time.Duration) ports.Ticker {
+	if d <= 0 {
+		panic("clock: non-positive interval for NewTicker")
+	}
+	w := &fakeWaiter{clock: f, ch: make(chan time.Time, 1), period: d}
+	f.mu.Lock()
+	f.schedule(w, f.now.Add(d))
+	f.mu.Unlock()
+	return &fakeTicker{w}
+}
+
+// Advance moves the clock forward by d and fires due timers in order.
+func (f *Fake) Advance(d time.Duration) {
+	f.mu.Lock()
+	f.setLocked(f.now.Add(d))
+	f.mu.Unlock()
+}
+
+// Set moves the clock to t. Moving backwards fires nothing, which is
+// useful for simulating wall clock rollback.
+func (f *Fake) Set(t time.Time) {
+	f.mu.Lock()
+	f.setLocked(t)
+	f.mu.Unlock()
+}
+
+// Waiters returns the number of active timers and tickers.
+func (f *Fake) Waiters() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.waiters)
+}
+
+// BlockUntil blocks until at least n timers or tickers are active. Tests
+// use it to make sure a goroutine is waiting before advancing time.
+func (f *Fake) BlockUntil(n int) {
+	for {
+		f.mu.Lock()
+		if len(f.waiters) >= n {
+			f.mu.Unlock()
+			return
+		}
+		ch := f.changed
+		f.mu.Unlock()
+		<-ch
+	}
+}
+
+func (f *Fake) setLocked(t time.Time) {
+	f.now = t
+	for len(f.waiters) > 0 && !f.waiters[0].when.After(t) {
+		w := f.waiters[0]
+		f.waiters = f.waiters[1:]
+		// Like time.Ticker, drop ticks the receiver is not keeping up with.
+		select {
+		case w.ch <- w.when:
+		default:
+		}
+		if w.period > 0 {
+			next := w.when.Add(w.period)
+			for !next.After(t) {
+				next = next.Add(w.period)
+			}
+			f.insert(w, next)
+		} else {
+			w.active = false
+		}
+	}
+	f.notify()
+}
+
+// schedule (re)arms w at when. Callers must hold f.mu.
+func (f *Fake) schedule(w *fakeWaiter, when time.Time) {
+	f.remove(w)
+	f.insert(w, when)
+	f.notify()
+}
+
+func (f *Fake) insert(w *fakeWaiter, when time.Time) {
+	w.when = when
+	w.active = true
+	i := sort.Search(len(f.waiters), func(i int) bool {
+		return f.waiters[i].when.After(when)
+	})
+	f.waiters = append(f.waiters, nil)
+	copy(f.waiters[i+1:], f.waiters[i:])
+	f.waiters[i] = w
+}
+
+func (f *Fake) remove(w *fakeWaiter) bool {
+	if !w.active {
+		return false
+	}
+	for i, x := range f.waiters {
+		if x == w {
+			f.waiters = append(f.waiters[:i], f.waiters[i+1:]...)
+			break
+		}
+	}
+	w.active = false
+	return true
+}
+
+func (f *Fake) notify() {
+	close(f.changed)
+	f.changed = make(chan struct{})
+}
+
+type fakeWaiter struct {
+	clock  *Fake
+	ch     chan time.Time
+	when   time.Time
+	period time.Duration
+	active bool
+}
+
+type fakeTimer struct{ w *fakeWaiter }
+
+func (t *fakeTimer) C() <-chan time.Time { return t.w.ch }
+
+func (t *fakeTimer) Stop() bool {
+	f := t.w.clock
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	ok := f.remove(t.w)
+	f.notify()
+	return ok
+}
+
+func (t *fakeTimer) Reset(d time.Duration) bool {
+	f := t.w.clock
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	active := t.w.active
+	f.schedule(t.w, f.now.Add(d))
+	f.setLocked(f.now)
+	return active
+}
+
+type fakeTicker struct{ w *fakeWaiter }
+
+func (t *fakeTicker) C() <-chan time.Time { return t.w.ch }
+
+func (t *fakeTicker) Stop() {
+	f := t.w.clock
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.remove(t.w)
+	f.notify()
+}
+
+func (t *fakeTicker) Reset(d time.Duration) {
+	if d <= 0 {
+		panic("clock: non-positive interval for Ticker.Reset")
+	}
+	f := t.w.clock
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	t.w.period = d
+	f.schedule(t.w, f.now.Add(d))
+}
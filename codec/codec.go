@@ -0,0 +1,226 @@
+package codec
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Encoder writes values in one media type.
+type Encoder interface {
+	// MediaType is the type matched against Accept and sent as
+	// Content-Type, e.g. "application/json".
+	MediaType() string
+	Encode(w io.Writer, v any) error
+}
+
+// Prettifier is implemented by encoders with a human-readable variant,
+// selected with the ?pretty query parameter.
+type Prettifier interface {
+	Pretty() Encoder
+}
+
+// Registry holds encoders keyed by media type. The first registered
+// encoder is the default when the client accepts anything.
+type Registry struct {
+	mu       sync.RWMutex
+	encoders []Encoder
+}
+
+// NewRegistry returns a registry with encs registered in order.
+func NewRegistry(encs ...Encoder) *Registry {
+	r := &Registry{}
+	for _, e := range encs {
+		r.Register(e)
+	}
+	return r
+}
+
+// Default serves JSON, XML and CSV, preferring JSON.
+var Default = NewRegistry(JSON{}, XML{}, CSV{})
+
+// Register adds e to the Default registry.
+func Register(e Encoder) { Default.Register(e) }
+
+// Register adds e, replacing any encoder with the same media type.
+func (r *Registry) Register(e Encoder) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, old := range r.encoders {
+		if strings.EqualFold(old.MediaType(), e.MediaType()) {
+			r.encoders[i] = e
+			return
+		}
+	}
+	r.encoders = append(r.encoders, e)
+}
+
+// Lookup returns the encoder registered for mediaType.
+func (r *Registry) Lookup(mediaType string) (Encoder, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, e := range r.encoders {
+		if strings.EqualFold(e.MediaType(), mediaType) {
+			return e, true
+		}
+	}
+	return nil, false
+}
+
+// MediaTypes lists registered media types in preference order.
+func (r *Registry) MediaTypes() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	out := make([]string, len(r.encoders))
+	for i, e := range r.encoders {
+		out[i] = e.MediaType()
+	}
+	return out
+}
+
+// Negotiate picks the encoder for req's Accept header, switching to the
+// pretty variant when ?pretty is set. It reports false when no
+// registered media type is acceptable.
+func (r *Registry) Negotiate(req *http.Request) (Encoder, bool) {
+	mt, ok := Negotiate(req.Header.Get("Accept"), r.MediaTypes())
+	if !ok {
+		return nil, false
+	}
+	e, ok := r.Lookup(mt)
+	if !ok {
+		return nil, false
+	}
+	if p, isPretty := e.(Prettifier); isPretty && WantsPretty(req) {
+		e = p.Pretty()
+	}
+	return e, true
+}
+
+// WantsPretty reports whether the request asks for pretty output with
+// ?pretty, ?pretty=1 or ?pretty=true.
+func WantsPretty(r *http.Request) bool {
+	if r.URL == nil {
+		return false
+	}
+	q := r.URL.Query()
+	if !q.Has("pretty") {
+		return false
+	}
+	v := q.Get("pretty")
+	if v == "" {
+		return true
+	}
+	b, err := strconv.ParseBool(v)
+	return err == nil && b
+}
+
+// Negotiate returns the offer best matching the Accept header value.
+// Ranges are ranked by q-value, then specificity, then their order in
+// the header; remaining ties go to the earlier offer. An empty header
+// accepts the first offer.
+func Negotiate(accept string, offers []string) (string, bool) {
+	if len(offers) == 0 {
+		return "", false
+	}
+	if strings.TrimSpace(accept) == "" {
+		return offers[0], true
+	}
+	ranges := parseAccept(accept)
+	best, bestIdx := rank{}, -1
+	for i, offer := range offers {
+		m, ok := match(ranges, offer)
+		if !ok || m.q <= 0 {
+			continue
+		}
+		if bestIdx < 0 || m.better(best) {
+			best, bestIdx = m, i
+		}
+	}
+	if bestIdx < 0 {
+		return "", false
+	}
+	return offers[bestIdx], true
+}
+
+type mediaRange struct {
+	typ, sub string
+	q        float64
+	pos      int
+}
+
+type rank struct {
+	q           float64
+	specificity int
+	pos         int
+}
+
+func (a rank) better(b rank) bool {
+	if a.q != b.q {
+		return a.q > b.q
+	}
+	if a.specificity != b.specificity {
+		return a.specificity > b.specificity
+	}
+	return a.pos < b.pos
+}
+
+func parseAccept(accept string) []mediaRange {
+	var out []mediaRange
+	for i, part := range strings.Split(accept, ",") {
+		fields := strings.Split(part, ";")
+		typ, sub, ok := strings.Cut(strings.ToLower(strings.TrimSpace(fields[0])), "/")
+		if !ok || typ == "" || sub == "" {
+			continue
+		}
+		mr := mediaRange{typ: typ, sub: sub, q: 1, pos: i}
+		valid := true
+		for _, p := range fields[1:] {
+			k, v, _ := strings.Cut(strings.TrimSpace(p), "=")
+			if !strings.EqualFold(k, "q") {
+				continue
+			}
+			q, err := strconv.ParseFloat(v, 64)
+			if err != nil || q < 0 || q > 1 {
+				valid = false
+				break
+			}
+			mr.q = q
+		}
+		if valid {
+			out = append(out, mr)
+		}
+	}
+	return out
+}
+
+// match finds the most specific range covering offer. A more specific
+// range takes precedence even with a lower q, so "*/*, text/csv;q=0"
+// excludes CSV.
+func match(ranges []mediaRange, offer string) (rank, bool) {
+	typ, sub, _ := strings.Cut(strings.ToLower(offer), "/")
+	if i := strings.IndexByte(sub, ';'); i >= 0 {
+		sub = strings.TrimSpace(sub[:i])
+	}
+	best, found := rank{}, false
+	for _, mr := range ranges {
+		var spec int
+		switch {
+		case mr.typ == typ && mr.sub == sub:
+			spec = 2
+		case mr.typ == typ && mr.sub == "*":
+			spec = 1
+		case mr.typ == "*" && mr.sub == "*":
+			spec = 0
+		default:
+			continue
+		}
+		r := rank{q: mr.q, specificity: spec, pos: mr.pos}
+		if !found || spec > best.specificity ||
+			(spec == best.specificity && r.pos < best.pos) {
+			best, found = r, true
+		}
+	}
+	return best, found
+}
@@ -0,0 +1,146 @@
+package codec
+
+import (
+	"encoding"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+)
+
+// CSV encodes text/csv. It accepts [][]string, a struct, or a slice of
+// structs (or struct pointers). Struct columns are named by the `csv`
+// tag, falling back to the `json` tag and then the field name; "-"
+// skips a field. Nested values are written as JSON.
+type CSV struct {
+	// Comma is the field delimiter. Defaults to ','.
+	Comma rune
+}
+
+func (CSV) MediaType() string { return "text/csv" }
+
+func (c CSV) Encode(w io.Writer, v any) error {
+	cw := csv.NewWriter(w)
+	if c.Comma != 0 {
+		cw.Comma = c.Comma
+	}
+	if rows, ok := v.([][]string); ok {
+		if err := cw.WriteAll(rows); err != nil {
+			return err
+		}
+		return cw.Error()
+	}
+
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	var items []reflect.Value
+	var elem reflect.Type
+	switch rv.Kind() {
+	case reflect.Struct:
+		items, elem = []reflect.Value{rv}, rv.Type()
+	case reflect.Slice, reflect.Array:
+		elem = rv.Type().Elem()
+		for i := 0; i < rv.Len(); i++ {
+			items = append(items, rv.Index(i))
+		}
+	}
+	for elem != nil && elem.Kind() == reflect.Pointer {
+		elem = elem.Elem()
+	}
+	if elem == nil || elem.Kind() != reflect.Struct {
+		return fmt.Errorf("codec: cannot encode %T as CSV", v)
+	}
+
+	cols := csvColumns(elem)
+	header := make([]string, len(cols))
+	for i, col := range cols {
+		header[i] = col.name
+	}
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	row := make([]string, len(cols))
+	for _, item := range items {
+		for item.Kind() == reflect.Pointer {
+			if item.IsNil() {
+				break
+			}
+			item = item.Elem()
+		}
+		if item.Kind() != reflect.Struct {
+			continue
+		}
+		for i, col := range cols {
+			f, err := item.FieldByIndexErr(col.index)
+			if err != nil {
+				row[i] = ""
+				continue
+			}
+			if row[i], err = csvCell(f); err != nil {
+				return err
+			}
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+type csvColumn struct {
+	name  string
+	index []int
+}
+
+func csvColumns(t reflect.Type) []csvColumn {
+	var cols []csvColumn
+	for _, sf := range reflect.VisibleFields(t) {
+		if !sf.IsExported() || sf.Anonymous && sf.Type.Kind() == reflect.Struct {
+			continue
+		}
+		name := sf.Name
+		if tag, ok := sf.Tag.Lookup("csv"); ok {
+			name, _, _ = strings.Cut(tag, ",")
+		} else if tag, ok := sf.Tag.Lookup("json"); ok {
+			if n, _, _ := strings.Cut(tag, ","); n != "" {
+				name = n
+			}
+		}
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = sf.Name
+		}
+		cols = append(cols, csvColumn{name: name, index: sf.Index})
+	}
+	return cols
+}
+
+func csvCell(v reflect.Value) (string, error) {
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface, reflect.Slice, reflect.Map:
+		if v.IsNil() {
+			return "", nil
+		}
+	}
+	if tm, ok := v.Interface().(encoding.TextMarshaler); ok {
+		b, err := tm.MarshalText()
+		return string(b), err
+	}
+	if v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		return csvCell(v.Elem())
+	}
+	switch v.Kind() {
+	case reflect.Struct, reflect.Map, reflect.Slice, reflect.Array:
+		b, err := json.Marshal(v.Interface())
+		return string(b), err
+	default:
+		return fmt.Sprint(v.Interface()), nil
+	}
+}
@@ -0,0 +1,29 @@
+package codec
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// JSON encodes application/json. Indent, when set, pretty-prints.
+type JSON struct {
+	Indent string
+}
+
+func (JSON) MediaType() string { return "application/json" }
+
+func (j JSON) Encode(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	if j.Indent != "" {
+		enc.SetIndent("", j.Indent)
+	}
+	return enc.Encode(v)
+}
+
+// Pretty implements Prettifier.
+func (j JSON) Pretty() Encoder {
+	if j.Indent == "" {
+		j.Indent = "  "
+	}
+	return j
+}
@@ -0,0 +1,161 @@
+package codec
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"unicode"
+)
+
+// XML encodes application/xml. Structs and xml.Marshaler values use
+// encoding/xml, so xml struct tags apply. Slices are wrapped in an
+// <items> root; maps and other values are encoded from their JSON form
+// (see EncodeXMLElement) under a <response> root.
+type XML struct {
+	Indent string
+}
+
+func (XML) MediaType() string { return "application/xml" }
+
+func (x XML) Encode(w io.Writer, v any) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	if x.Indent != "" {
+		enc.Indent("", x.Indent)
+	}
+	if err := x.encode(enc, v); err != nil {
+		return err
+	}
+	if err := enc.Close(); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func (x XML) encode(enc *xml.Encoder, v any) error {
+	if isXMLNative(v) {
+		return enc.Encode(v)
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array {
+		if isXMLNative(reflect.Zero(rv.Type().Elem()).Interface()) {
+			start := xml.StartElement{Name: xml.Name{Local: "items"}}
+			if err := enc.EncodeToken(start); err != nil {
+				return err
+			}
+			for i := 0; i < rv.Len(); i++ {
+				if err := enc.Encode(rv.Index(i).Interface()); err != nil {
+					return err
+				}
+			}
+			return enc.EncodeToken(start.End())
+		}
+		return EncodeXMLElement(enc, "items", v)
+	}
+	return EncodeXMLElement(enc, "response", v)
+}
+
+// Pretty implements Prettifier.
+func (x XML) Pretty() Encoder {
+	if x.Indent == "" {
+		x.Indent = "  "
+	}
+	return x
+}
+
+func isXMLNative(v any) bool {
+	if _, ok := v.(xml.Marshaler); ok {
+		return true
+	}
+	t := reflect.TypeOf(v)
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t != nil && t.Kind() == reflect.Struct
+}
+
+// EncodeXMLElement writes v as an element called name. xml.Marshaler
+// values encode themselves; anything else is converted through its JSON
+// form so field names match the JSON representation. Objects become
+// child elements in key order and arrays become repeated <i> elements,
+// following the RFC 7807 XML mapping. Nil values produce no element.
+func EncodeXMLElement(enc *xml.Encoder, name string, v any) error {
+	if v == nil {
+		return nil
+	}
+	if m, ok := v.(xml.Marshaler); ok {
+		return enc.EncodeElement(m, xml.StartElement{Name: xml.Name{Local: name}})
+	}
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var generic any
+	if err := dec.Decode(&generic); err != nil {
+		return err
+	}
+	return encodeGeneric(enc, name, generic)
+}
+
+func encodeGeneric(enc *xml.Encoder, name string, v any) error {
+	start := xml.StartElement{Name: xml.Name{Local: xmlName(name)}}
+	switch t := v.(type) {
+	case nil:
+		return nil
+	case map[string]any:
+		keys := make([]string, 0, len(t))
+		for k := range t {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		if err := enc.EncodeToken(start); err != nil {
+			return err
+		}
+		for _, k := range keys {
+			if err := encodeGeneric(enc, k, t[k]); err != nil {
+				return err
+			}
+		}
+		return enc.EncodeToken(start.End())
+	case []any:
+		if err := enc.EncodeToken(start); err != nil {
+			return err
+		}
+		for _, item := range t {
+			if err := encodeGeneric(enc, "i", item); err != nil {
+				return err
+			}
+		}
+		return enc.EncodeToken(start.End())
+	case string, bool, json.Number:
+		return enc.EncodeElement(fmt.Sprint(t), start)
+	default:
+		return fmt.Errorf("codec: unexpected JSON value %T", v)
+	}
+}
+
+// xmlName makes a JSON key usable as an element name by replacing
+// characters XML does not allow.
+func xmlName(s string) string {
+	if s == "" {
+		return "_"
+	}
+	out := []rune(s)
+	for i, r := range out {
+		ok := r == '_' || unicode.IsLetter(r) ||
+			(i > 0 && (r == '-' || r == '.' || unicode.IsDigit(r)))
+		if !ok {
+			out[i] = '_'
+		}
+	}
+	return string(out)
+}
@@ -5,6 +5,7 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/aatuh/api-toolkit/redact"
 	"github.com/aatuh/envvar"
 )
 
@@ -191,8 +192,11 @@ func (a *Adapter) MustBindWithPrefix(dst any, prefix string) {
 	}
 }
 
-// DumpRedacted returns environment with secrets redacted.
+// DumpRedacted returns environment with secrets redacted. Secret-looking
+// keys are masked entirely and values such as DSNs have embedded
+// credentials masked.
 func (a *Adapter) DumpRedacted() map[string]string {
+	red := redact.Default()
 	env := os.Environ()
 	out := make(map[string]string, len(env))
 	for _, kv := range env {
@@ -200,14 +204,10 @@ func (a *Adapter) DumpRedacted() map[string]string {
 		if !ok {
 			continue
 		}
-		upper := strings.ToUpper(k)
-		if strings.Contains(upper, "SECRET") ||
-			strings.Contains(upper, "TOKEN") ||
-			strings.Contains(upper, "PASSWORD") ||
-			strings.HasSuffix(upper, "_KEY") {
-			out[k] = "***"
+		if red.IsSecretKey(k) {
+			out[k] = redact.Mask
 		} else {
-			out[k] = v
+			out[k] = red.String(v)
 		}
 	}
 	return out
@@ -6,6 +6,7 @@ import (
 	"runtime"
 	"time"
 
+	"github.com/aatuh/api-toolkit/clock"
 	"github.com/aatuh/api-toolkit/ports"
 )
 
@@ -132,6 +133,7 @@ type CustomChecker struct {
 	name      string
 	checkFunc func(ctx context.Context) (ports.HealthStatus, string, interface{})
 	timeout   time.Duration
+	clock     ports.Clock
 }
 
 func NewCustomChecker(name string, checkFunc func(ctx context.Context) (ports.HealthStatus, string, interface{})) ports.HealthChecker {
@@ -139,6 +141,7 @@ func NewCustomChecker(name string, checkFunc func(ctx context.Context) (ports.He
 		name:      name,
 		checkFunc: checkFunc,
 		timeout:   5 * time.Second,
+		clock:     clock.NewSystemClock(),
 	}
 }
 
@@ -147,6 +150,18 @@ func NewCustomCheckerWithTimeout(name string, timeout time.Duration, checkFunc f
 		name:      name,
 		checkFunc: checkFunc,
 		timeout:   timeout,
+		clock:     clock.NewSystemClock(),
+	}
+}
+
+// NewCustomCheckerWithClock is NewCustomCheckerWithTimeout with the timeout
+// and timings driven by clk.
+func NewCustomCheckerWithClock(name string, timeout time.Duration, clk ports.Clock, checkFunc func(ctx context.Context) (ports.HealthStatus, string, interface{})) ports.HealthChecker {
+	return &CustomChecker{
+		name:      name,
+		checkFunc: checkFunc,
+		timeout:   timeout,
+		clock:     clock.OrSystem(clk),
 	}
 }
 
@@ -155,20 +170,20 @@ func (c *CustomChecker) Name() string {
 }
 
 func (c *CustomChecker) Check(ctx context.Context) ports.HealthResult {
-	start := time.Now()
+	start := c.clock.Now()
 
 	// Create context with timeout
-	checkCtx, cancel := context.WithTimeout(ctx, c.timeout)
+	checkCtx, cancel := clock.WithTimeout(ctx, c.clock, c.timeout)
 	defer cancel()
 
 	status, message, details := c.checkFunc(checkCtx)
-	duration := time.Since(start)
+	duration := c.clock.Since(start)
 
 	return ports.HealthResult{
 		Status:    status,
 		Message:   message,
 		Details:   details,
-		Timestamp: time.Now(),
+		Timestamp: c.clock.Now(),
 		Duration:  duration,
 	}
 }
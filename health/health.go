@@ -6,12 +6,14 @@ import (
 	"sync"
 	"time"
 
+	"github.com/aatuh/api-toolkit/clock"
 	"github.com/aatuh/api-toolkit/ports"
 )
 
 // Manager implements ports.HealthManager for managing health checks.
 type Manager struct {
 	config     ports.HealthCheckConfig
+	clock      ports.Clock
 	checkers   map[string]ports.HealthChecker
 	cache      map[string]ports.HealthResult
 	cacheMutex sync.RWMutex
@@ -32,8 +34,15 @@ func New() ports.HealthManager {
 
 // NewWithConfig creates a new health manager with custom configuration.
 func NewWithConfig(config ports.HealthCheckConfig) ports.HealthManager {
+	return NewWithClock(config, nil)
+}
+
+// NewWithClock creates a health manager whose timeouts, cache expiry and
+// timestamps follow clk. A nil clk uses the system clock.
+func NewWithClock(config ports.HealthCheckConfig, clk ports.Clock) ports.HealthManager {
 	return &Manager{
 		config:   config,
+		clock:    clock.OrSystem(clk),
 		checkers: make(map[string]ports.HealthChecker),
 		cache:    make(map[string]ports.HealthResult),
 	}
@@ -115,7 +124,7 @@ func (m *Manager) GetDetailedHealth(ctx context.Context) ports.DetailedHealthRes
 
 	return ports.DetailedHealthResponse{
 		Status:    overallStatus,
-		Timestamp: time.Now(),
+		Timestamp: m.clock.Now(),
 		Checks:    checks,
 		Summary:   summary,
 	}
@@ -127,12 +136,12 @@ func (m *Manager) performChecks(ctx context.Context, checkerNames []string) port
 		return ports.HealthResult{
 			Status:    ports.HealthStatusHealthy,
 			Message:   "No checks configured",
-			Timestamp: time.Now(),
+			Timestamp: m.clock.Now(),
 		}
 	}
 
 	// Create context with timeout
-	checkCtx, cancel := context.WithTimeout(ctx, m.config.Timeout)
+	checkCtx, cancel := clock.WithTimeout(ctx, m.clock, m.config.Timeout)
 	defer cancel()
 
 	results := make([]ports.HealthResult, 0, len(checkerNames))
@@ -180,7 +189,7 @@ func (m *Manager) performChecks(ctx context.Context, checkerNames []string) port
 	return ports.HealthResult{
 		Status:    overallStatus,
 		Message:   message,
-		Timestamp: time.Now(),
+		Timestamp: m.clock.Now(),
 	}
 }
 
@@ -190,7 +199,7 @@ func (m *Manager) performCheck(ctx context.Context, name string) ports.HealthRes
 	if m.config.EnableCaching {
 		m.cacheMutex.RLock()
 		if cached, exists := m.cache[name]; exists {
-			if time.Since(cached.Timestamp) < m.config.CacheDuration {
+			if m.clock.Since(cached.Timestamp) < m.config.CacheDuration {
 				m.cacheMutex.RUnlock()
 				return cached
 			}
@@ -207,15 +216,15 @@ func (m *Manager) performCheck(ctx context.Context, name string) ports.HealthRes
 		return ports.HealthResult{
 			Status:    ports.HealthStatusUnknown,
 			Message:   fmt.Sprintf("Checker '%s' not found", name),
-			Timestamp: time.Now(),
+			Timestamp: m.clock.Now(),
 		}
 	}
 
 	// Perform check
-	start := time.Now()
+	start := m.clock.Now()
 	result := checker.Check(ctx)
-	result.Duration = time.Since(start)
-	result.Timestamp = time.Now()
+	result.Duration = m.clock.Since(start)
+	result.Timestamp = m.clock.Now()
 
 	// Cache result
 	if m.config.EnableCaching {
@@ -0,0 +1,145 @@
+package httpclient
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/aatuh/api-toolkit/httpx"
+	"github.com/aatuh/api-toolkit/ports"
+)
+
+// ErrCircuitOpen is returned without contacting the host while its
+// circuit is open. It renders as 503 through httpx.WriteError.
+var ErrCircuitOpen error = &circuitOpenError{}
+
+type circuitOpenError struct{}
+
+func (*circuitOpenError) Error() string { return "httpclient: circuit open" }
+
+// Problem implements httpx.ProblemError.
+func (*circuitOpenError) Problem() httpx.Problem {
+	return httpx.Problem{
+		Title:  http.StatusText(http.StatusServiceUnavailable),
+		Status: http.StatusServiceUnavailable,
+		Detail: "an upstream dependency is unavailable",
+	}
+}
+
+// BreakerOptions configures the per-host circuit breaker. A host's
+// circuit opens after FailureThreshold consecutive failures (transport
+// errors or 5xx), rejects calls with ErrCircuitOpen for OpenTimeout, then
+// lets one probe through: success closes it, failure reopens it.
+type BreakerOptions struct {
+	Disabled bool
+	// FailureThreshold defaults to 5.
+	FailureThreshold int
+	// OpenTimeout defaults to 30s.
+	OpenTimeout time.Duration
+	// IsFailure overrides what counts as a failure.
+	IsFailure func(resp *http.Response, err error) bool
+}
+
+type breakerState int
+
+const (
+	stateClosed breakerState = iota
+	stateOpen
+	stateHalfOpen
+)
+
+type breaker struct {
+	mu       sync.Mutex
+	state    breakerState
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+type breakers struct {
+	next  http.RoundTripper
+	opts  BreakerOptions
+	clk   ports.Clock
+	mu    sync.Mutex
+	hosts map[string]*breaker
+}
+
+func newBreakers(next http.RoundTripper, opts BreakerOptions, clk ports.Clock) *breakers {
+	if opts.FailureThreshold <= 0 {
+		opts.FailureThreshold = 5
+	}
+	if opts.OpenTimeout <= 0 {
+		opts.OpenTimeout = 30 * time.Second
+	}
+	if opts.IsFailure == nil {
+		opts.IsFailure = func(resp *http.Response, err error) bool {
+			return err != nil || resp.StatusCode >= http.StatusInternalServerError
+		}
+	}
+	return &breakers{next: next, opts: opts, clk: clk, hosts: map[string]*breaker{}}
+}
+
+func (t *breakers) RoundTrip(req *http.Request) (*http.Response, error) {
+	b := t.host(req.URL.Host)
+	if !b.allow(t.clk.Now(), t.opts.OpenTimeout) {
+		return nil, ErrCircuitOpen
+	}
+	resp, err := t.next.RoundTrip(req)
+	if err != nil && req.Context().Err() != nil {
+		// The caller gave up; that says nothing about the host.
+		b.release()
+		return resp, err
+	}
+	b.record(t.opts.IsFailure(resp, err), t.clk.Now(), t.opts.FailureThreshold)
+	return resp, err
+}
+
+func (t *breakers) host(h string) *breaker {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	b, ok := t.hosts[h]
+	if !ok {
+		b = &breaker{}
+		t.hosts[h] = b
+	}
+	return b
+}
+
+func (b *breaker) allow(now time.Time, openTimeout time.Duration) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case stateOpen:
+		if now.Sub(b.openedAt) < openTimeout {
+			return false
+		}
+		b.state = stateHalfOpen
+		fallthrough
+	case stateHalfOpen:
+		if b.probing {
+			return false
+		}
+		b.probing = true
+	}
+	return true
+}
+
+func (b *breaker) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+}
+
+func (b *breaker) record(failed bool, now time.Time, threshold int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+	if !failed {
+		b.state, b.failures = stateClosed, 0
+		return
+	}
+	b.failures++
+	if b.state == stateHalfOpen || b.failures >= threshold {
+		b.state, b.openedAt = stateOpen, now
+	}
+}
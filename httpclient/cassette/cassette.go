@@ -0,0 +1,379 @@
+package cassette
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/aatuh/api-toolkit/redact"
+)
+
+// ErrUnmatched is returned (wrapped) when replaying a request that no
+// recorded interaction matches.
+var ErrUnmatched = errors.New("cassette: no recorded interaction matches request")
+
+// Mode selects between recording and replaying.
+type Mode int
+
+const (
+	// ModeReplay serves only recorded interactions and never touches the
+	// network. It is the zero value.
+	ModeReplay Mode = iota
+	// ModeRecord forwards every request to the real transport and
+	// records it, replacing the cassette on Close.
+	ModeRecord
+	// ModeAuto replays when the cassette file exists and records
+	// otherwise.
+	ModeAuto
+)
+
+// Options configures a Recorder.
+type Options struct {
+	// Path is the JSON cassette file.
+	Path string
+	Mode Mode
+	// Real performs requests while recording. Defaults to
+	// http.DefaultTransport.
+	Real http.RoundTripper
+	// Match controls how requests are matched on replay.
+	Match MatchOptions
+	// RedactHeaders lists extra header names to mask when recording.
+	// Headers and query parameters that redact.Default considers secret
+	// (Authorization, Cookie, *_TOKEN, ...) are always masked.
+	RedactHeaders []string
+}
+
+// MatchOptions selects the request parts compared on replay. The zero
+// value matches method, URL and body. JSON bodies are compared
+// semantically.
+type MatchOptions struct {
+	IgnoreMethod bool
+	IgnoreURL    bool
+	IgnoreBody   bool
+	// Headers must be equal on both requests.
+	Headers []string
+	// Custom, when set, must also accept the pair.
+	Custom func(req *http.Request, body []byte, rec Request) bool
+}
+
+// Interaction is one recorded request/response pair.
+type Interaction struct {
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+}
+
+// Request is the recorded form of an outgoing request.
+type Request struct {
+	Method       string      `json:"method"`
+	URL          string      `json:"url"`
+	Header       http.Header `json:"header,omitempty"`
+	Body         string      `json:"body,omitempty"`
+	BodyEncoding string      `json:"body_encoding,omitempty"`
+}
+
+// Response is the recorded form of a response.
+type Response struct {
+	StatusCode   int         `json:"status_code"`
+	Header       http.Header `json:"header,omitempty"`
+	Body         string      `json:"body,omitempty"`
+	BodyEncoding string      `json:"body_encoding,omitempty"`
+}
+
+type file struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+// Recorder is an http.RoundTripper that records or replays interactions.
+// Use it as any client's Transport and Close it when done so recordings
+// are written.
+type Recorder struct {
+	opts      Options
+	recording bool
+	redactor  *redact.Redactor
+	extra     map[string]bool
+
+	mu           sync.Mutex
+	interactions []Interaction
+	used         []bool
+}
+
+// New loads the cassette for replay or prepares a new one for recording.
+func New(opts Options) (*Recorder, error) {
+	if opts.Path == "" {
+		return nil, errors.New("cassette: Path is required")
+	}
+	if opts.Real == nil {
+		opts.Real = http.DefaultTransport
+	}
+	r := &Recorder{opts: opts, redactor: redact.Default(), extra: map[string]bool{}}
+	for _, h := range opts.RedactHeaders {
+		r.extra[http.CanonicalHeaderKey(h)] = true
+	}
+	switch opts.Mode {
+	case ModeRecord:
+		r.recording = true
+		return r, nil
+	case ModeAuto:
+		if _, err := os.Stat(opts.Path); errors.Is(err, os.ErrNotExist) {
+			r.recording = true
+			return r, nil
+		}
+	}
+	data, err := os.ReadFile(opts.Path)
+	if err != nil {
+		return nil, fmt.Errorf("cassette: %w", err)
+	}
+	var f file
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("cassette: %s: %w", opts.Path, err)
+	}
+	r.interactions = f.Interactions
+	r.used = make([]bool, len(f.Interactions))
+	return r, nil
+}
+
+// Recording reports whether the recorder talks to the real transport.
+func (r *Recorder) Recording() bool { return r.recording }
+
+// Client returns an http.Client using the recorder as transport.
+func (r *Recorder) Client() *http.Client { return &http.Client{Transport: r} }
+
+// RoundTrip implements http.RoundTripper.
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := readBody(req)
+	if err != nil {
+		return nil, err
+	}
+	if r.recording {
+		return r.record(req, body)
+	}
+	return r.replay(req, body)
+}
+
+func (r *Recorder) replay(req *http.Request, body []byte) (*http.Response, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, in := range r.interactions {
+		if r.used[i] || !r.matches(req, body, in.Request) {
+			continue
+		}
+		r.used[i] = true
+		respBody, err := decodeBody(in.Response.Body, in.Response.BodyEncoding)
+		if err != nil {
+			return nil, err
+		}
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", in.Response.StatusCode, http.StatusText(in.Response.StatusCode)),
+			StatusCode:    in.Response.StatusCode,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        in.Response.Header.Clone(),
+			Body:          io.NopCloser(bytes.NewReader(respBody)),
+			ContentLength: int64(len(respBody)),
+			Request:       req,
+		}, nil
+	}
+	return nil, fmt.Errorf("%w: %s %s", ErrUnmatched, req.Method, r.redactURL(req.URL))
+}
+
+func (r *Recorder) record(req *http.Request, body []byte) (*http.Response, error) {
+	resp, err := r.opts.Real.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+	in := Interaction{
+		Request: Request{
+			Method: req.Method,
+			URL:    r.redactURL(req.URL),
+			Header: r.redactHeader(req.Header),
+		},
+		Response: Response{
+			StatusCode: resp.StatusCode,
+			Header:     r.redactHeader(resp.Header),
+		},
+	}
+	in.Request.Body, in.Request.BodyEncoding = encodeBody(body)
+	in.Response.Body, in.Response.BodyEncoding = encodeBody(respBody)
+
+	r.mu.Lock()
+	r.interactions = append(r.interactions, in)
+	r.used = append(r.used, true)
+	r.mu.Unlock()
+	return resp, nil
+}
+
+// Unused returns recorded interactions that were never replayed, which
+// usually means the code under test made fewer calls than expected.
+func (r *Recorder) Unused() []Interaction {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var out []Interaction
+	for i, in := range r.interactions {
+		if !r.used[i] {
+			out = append(out, in)
+		}
+	}
+	return out
+}
+
+// Close writes the cassette when recording. Replaying needs no cleanup.
+func (r *Recorder) Close() error {
+	if !r.recording {
+		return nil
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	r.mu.Lock()
+	err := enc.Encode(file{Interactions: r.interactions})
+	r.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(r.opts.Path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(r.opts.Path, buf.Bytes(), 0o644)
+}
+
+func (r *Recorder) matches(req *http.Request, body []byte, rec Request) bool {
+	m := r.opts.Match
+	if !m.IgnoreMethod && req.Method != rec.Method {
+		return false
+	}
+	if !m.IgnoreURL && r.redactURL(req.URL) != rec.URL {
+		return false
+	}
+	if !m.IgnoreBody {
+		recBody, err := decodeBody(rec.Body, rec.BodyEncoding)
+		if err != nil || !sameBody(body, recBody) {
+			return false
+		}
+	}
+	for _, h := range m.Headers {
+		if r.redactValue(h, req.Header.Get(h)) != rec.Header.Get(h) {
+			return false
+		}
+	}
+	return m.Custom == nil || m.Custom(req, body, rec)
+}
+
+func (r *Recorder) secret(name string) bool {
+	return r.extra[http.CanonicalHeaderKey(name)] || r.redactor.IsSecretKey(name)
+}
+
+func (r *Recorder) redactValue(name, v string) string {
+	if v != "" && r.secret(name) {
+		return redact.Mask
+	}
+	return v
+}
+
+func (r *Recorder) redactHeader(h http.Header) http.Header {
+	if len(h) == 0 {
+		return nil
+	}
+	out := make(http.Header, len(h))
+	for k, vs := range h {
+		if r.secret(k) {
+			out[k] = []string{redact.Mask}
+			continue
+		}
+		out[k] = append([]string(nil), vs...)
+	}
+	return out
+}
+
+// redactURL masks secret-looking query parameters so recordings and
+// matching never depend on credentials.
+func (r *Recorder) redactURL(u *url.URL) string {
+	if u.RawQuery == "" {
+		return u.String()
+	}
+	q := u.Query()
+	changed := false
+	for k := range q {
+		if r.secret(k) {
+			q[k] = []string{redact.Mask}
+			changed = true
+		}
+	}
+	if !changed {
+		return u.String()
+	}
+	c := *u
+	c.RawQuery = q.Encode()
+	return c.String()
+}
+
+func readBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	if req.GetBody != nil {
+		rc, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		defer rc.Close()
+		return io.ReadAll(rc)
+	}
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
+
+func encodeBody(b []byte) (string, string) {
+	if utf8.Valid(b) {
+		return string(b), ""
+	}
+	return base64.StdEncoding.EncodeToString(b), "base64"
+}
+
+func decodeBody(s, encoding string) ([]byte, error) {
+	if encoding == "base64" {
+		return base64.StdEncoding.DecodeString(s)
+	}
+	return []byte(s), nil
+}
+
+// sameBody compares bodies, treating JSON documents as equal when they
+// decode to the same value.
+func sameBody(a, b []byte) bool {
+	if bytes.Equal(a, b) {
+		return true
+	}
+	trimmed := strings.TrimSpace(string(a))
+	if trimmed == "" || (trimmed[0] != '{' && trimmed[0] != '[') {
+		return false
+	}
+	var va, vb any
+	if json.Unmarshal(a, &va) != nil || json.Unmarshal(b, &vb) != nil {
+		return false
+	}
+	return reflect.DeepEqual(va, vb)
+}
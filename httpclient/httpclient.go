@@ -0,0 +1,136 @@
+package httpclient
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/aatuh/api-toolkit/chi"
+	"github.com/aatuh/api-toolkit/clock"
+	"github.com/aatuh/api-toolkit/logctx"
+	"github.com/aatuh/api-toolkit/middleware/metrics"
+	"github.com/aatuh/api-toolkit/middleware/trace"
+	"github.com/aatuh/api-toolkit/ports"
+)
+
+// Options configures New and NewTransport.
+type Options struct {
+	// Timeout bounds a whole call including retries. Defaults to 30s;
+	// negative disables it.
+	Timeout time.Duration
+	// Base performs the actual round trips. Defaults to a clone of
+	// http.DefaultTransport.
+	Base    http.RoundTripper
+	Retry   RetryOptions
+	Breaker BreakerOptions
+	// Metrics records http_client_requests_total and
+	// http_client_request_duration_seconds per attempt, labelled by
+	// method, host and status. Defaults to metrics.NoopMetrics.
+	Metrics metrics.MetricsRecorder
+	// Log receives attempt failures when the request context carries no
+	// request-scoped logger (see logctx). Defaults to a no-op logger.
+	Log   ports.Logger
+	Clock ports.Clock
+}
+
+// New returns an http.Client whose transport propagates trace context and
+// request IDs, retries idempotent requests, trips a per-host circuit
+// breaker and records metrics. See NewTransport.
+func New(opts Options) *http.Client {
+	timeout := opts.Timeout
+	if timeout == 0 {
+		timeout = 30 * time.Second
+	}
+	if timeout < 0 {
+		timeout = 0
+	}
+	return &http.Client{Transport: NewTransport(opts), Timeout: timeout}
+}
+
+// NewTransport builds the transport chain, outermost first: propagation,
+// retry, circuit breaker, instrumentation, then Base. Each retry attempt
+// is instrumented and counted by the breaker separately.
+func NewTransport(opts Options) http.RoundTripper {
+	base := opts.Base
+	if base == nil {
+		base = http.DefaultTransport.(*http.Transport).Clone()
+	}
+	rec := opts.Metrics
+	if rec == nil {
+		rec = metrics.NoopMetrics{}
+	}
+	log := opts.Log
+	if log == nil {
+		log = logctx.Nop()
+	}
+	clk := clock.OrSystem(opts.Clock)
+
+	var rt http.RoundTripper = &instrumented{next: base, rec: rec, log: log, clk: clk}
+	if !opts.Breaker.Disabled {
+		rt = newBreakers(rt, opts.Breaker, clk)
+	}
+	if opts.Retry.MaxAttempts != 1 {
+		rt = newRetrier(rt, opts.Retry, clk, log)
+	}
+	return &propagating{next: rt}
+}
+
+// RequestIDHeader carries the inbound request ID to upstreams.
+var RequestIDHeader = "X-Request-Id"
+
+// propagating copies correlation IDs from the request context into
+// outgoing headers, without overwriting headers set by the caller.
+type propagating struct {
+	next http.RoundTripper
+}
+
+func (p *propagating) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	tp := trace.TraceParentFromContext(ctx)
+	rid := chi.RequestIDFromContext(ctx)
+	if (tp != "" && req.Header.Get("traceparent") == "") ||
+		(rid != "" && req.Header.Get(RequestIDHeader) == "") {
+		// RoundTrippers must not modify the caller's request.
+		req = req.Clone(ctx)
+		if tp != "" && req.Header.Get("traceparent") == "" {
+			req.Header.Set("traceparent", tp)
+		}
+		if rid != "" && req.Header.Get(RequestIDHeader) == "" {
+			req.Header.Set(RequestIDHeader, rid)
+		}
+	}
+	return p.next.RoundTrip(req)
+}
+
+// instrumented records metrics for each attempt and logs failures.
+type instrumented struct {
+	next http.RoundTripper
+	rec  metrics.MetricsRecorder
+	log  ports.Logger
+	clk  ports.Clock
+}
+
+func (t *instrumented) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := t.clk.Now()
+	resp, err := t.next.RoundTrip(req)
+	status := "error"
+	if err == nil {
+		status = strconv.Itoa(resp.StatusCode)
+	}
+	labels := metrics.Labels{"method": req.Method, "host": req.URL.Host, "status": status}
+	t.rec.IncCounter("http_client_requests_total", labels)
+	t.rec.ObserveHistogram("http_client_request_duration_seconds",
+		t.clk.Since(start).Seconds(), labels)
+	if err != nil || resp.StatusCode >= http.StatusInternalServerError {
+		log := t.log
+		if l, ok := logctx.Lookup(req.Context()); ok {
+			log = l
+		}
+		kv := []any{"method", req.Method, "host", req.URL.Host, "path", req.URL.Path, "status", status}
+		if err != nil {
+			kv = append(kv, "error", err)
+		}
+		log.Warn("upstream request failed", kv...)
+	}
+	return resp, err
+}
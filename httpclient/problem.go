@@ -0,0 +1,86 @@
+package httpclient
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/aatuh/api-toolkit/httpx"
+)
+
+// maxErrorBody caps how much of an error response is read.
+const maxErrorBody = 64 << 10
+
+// UpstreamError is an upstream error response. Upstream holds the decoded
+// application/problem+json body, or a Problem synthesized from the status
+// line and body text for other content types.
+type UpstreamError struct {
+	StatusCode int
+	Upstream   httpx.Problem
+}
+
+func (e *UpstreamError) Error() string {
+	msg := fmt.Sprintf("upstream responded %d", e.StatusCode)
+	if e.Upstream.Title != "" {
+		msg += " " + e.Upstream.Title
+	}
+	if e.Upstream.Detail != "" {
+		msg += ": " + e.Upstream.Detail
+	}
+	return msg
+}
+
+// Problem implements httpx.ProblemError. Upstream details are not passed
+// on to our own clients; they see a 502.
+func (e *UpstreamError) Problem() httpx.Problem {
+	return httpx.Problem{
+		Title:  http.StatusText(http.StatusBadGateway),
+		Status: http.StatusBadGateway,
+		Detail: "an upstream dependency returned an error",
+	}
+}
+
+// CheckResponse returns nil for 1xx-3xx responses. Otherwise it reads
+// (up to 64 KiB) and closes the body and returns an *UpstreamError.
+func CheckResponse(resp *http.Response) error {
+	if resp.StatusCode < http.StatusBadRequest {
+		return nil
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+	e := &UpstreamError{StatusCode: resp.StatusCode}
+	if isProblemJSON(resp.Header.Get("Content-Type")) && json.Unmarshal(body, &e.Upstream) == nil {
+		if e.Upstream.Status == 0 {
+			e.Upstream.Status = resp.StatusCode
+		}
+		return e
+	}
+	e.Upstream = httpx.Problem{
+		Title:  http.StatusText(resp.StatusCode),
+		Status: resp.StatusCode,
+		Detail: strings.TrimSpace(string(body)),
+	}
+	return e
+}
+
+// AsProblem decodes an upstream problem+json error, consuming the body.
+// It reports false, leaving the body unread, for anything else.
+func AsProblem(resp *http.Response) (httpx.Problem, bool) {
+	if resp.StatusCode < http.StatusBadRequest || !isProblemJSON(resp.Header.Get("Content-Type")) {
+		return httpx.Problem{}, false
+	}
+	var ue *UpstreamError
+	if !errors.As(CheckResponse(resp), &ue) {
+		return httpx.Problem{}, false
+	}
+	return ue.Upstream, true
+}
+
+func isProblemJSON(ct string) bool {
+	mt, _, err := mime.ParseMediaType(ct)
+	return err == nil && mt == httpx.ProblemJSON
+}
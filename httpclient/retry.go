@@ -0,0 +1,184 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/aatuh/api-toolkit/logctx"
+	"github.com/aatuh/api-toolkit/ports"
+)
+
+// RetryOptions configures retries. Only idempotent requests are retried:
+// GET, HEAD, OPTIONS, TRACE, PUT and DELETE, plus any request carrying an
+// Idempotency-Key header. Bodies must be replayable (req.GetBody, which
+// http.NewRequest sets for in-memory bodies).
+type RetryOptions struct {
+	// MaxAttempts includes the first try. Defaults to 3; 1 disables
+	// retries.
+	MaxAttempts int
+	// BaseDelay and MaxDelay bound the exponential backoff with full
+	// jitter. Default 100ms and 2s.
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+	// MaxRetryAfter is the longest Retry-After that is honoured; longer
+	// waits return the response instead. Defaults to 30s.
+	MaxRetryAfter time.Duration
+	// Retryable decides whether an attempt's outcome is retried. Defaults
+	// to DefaultRetryable.
+	Retryable func(resp *http.Response, err error) bool
+}
+
+// DefaultRetryable retries transport errors (except cancellation and an
+// open circuit) and 429, 502, 503 and 504 responses.
+func DefaultRetryable(resp *http.Response, err error) bool {
+	if err != nil {
+		return !errors.Is(err, context.Canceled) &&
+			!errors.Is(err, context.DeadlineExceeded) &&
+			!errors.Is(err, ErrCircuitOpen)
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+type retrier struct {
+	next http.RoundTripper
+	opts RetryOptions
+	clk  ports.Clock
+	log  ports.Logger
+}
+
+func newRetrier(next http.RoundTripper, opts RetryOptions, clk ports.Clock, log ports.Logger) *retrier {
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = 3
+	}
+	if opts.BaseDelay <= 0 {
+		opts.BaseDelay = 100 * time.Millisecond
+	}
+	if opts.MaxDelay <= 0 {
+		opts.MaxDelay = 2 * time.Second
+	}
+	if opts.MaxRetryAfter <= 0 {
+		opts.MaxRetryAfter = 30 * time.Second
+	}
+	if opts.Retryable == nil {
+		opts.Retryable = DefaultRetryable
+	}
+	return &retrier{next: next, opts: opts, clk: clk, log: log}
+}
+
+func (t *retrier) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !replayable(req) {
+		return t.next.RoundTrip(req)
+	}
+	ctx := req.Context()
+	for attempt := 1; ; attempt++ {
+		resp, err := t.next.RoundTrip(req)
+		if attempt >= t.opts.MaxAttempts || !t.opts.Retryable(resp, err) {
+			return resp, err
+		}
+		delay := t.backoff(attempt)
+		if resp != nil {
+			if ra, ok := retryAfter(resp.Header.Get("Retry-After"), t.clk.Now()); ok {
+				if ra > t.opts.MaxRetryAfter {
+					return resp, nil
+				}
+				delay = ra
+			}
+		}
+		if deadline, ok := ctx.Deadline(); ok && t.clk.Now().Add(delay).After(deadline) {
+			return resp, err
+		}
+		if resp != nil {
+			// Drain so the connection can be reused.
+			_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+			resp.Body.Close()
+		}
+		log := t.log
+		if l, ok := logctx.Lookup(ctx); ok {
+			log = l
+		}
+		log.Debug("retrying upstream request", "method", req.Method,
+			"host", req.URL.Host, "attempt", attempt+1, "delay_ms", delay.Milliseconds())
+		if err := sleep(ctx, t.clk, delay); err != nil {
+			return nil, err
+		}
+		if req, err = rewind(req); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// backoff returns a full-jitter delay for the given attempt (1-based).
+func (t *retrier) backoff(attempt int) time.Duration {
+	ceiling := t.opts.BaseDelay << (attempt - 1)
+	if ceiling <= 0 || ceiling > t.opts.MaxDelay {
+		ceiling = t.opts.MaxDelay
+	}
+	return time.Duration(rand.Int64N(int64(ceiling) + 1))
+}
+
+func replayable(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace,
+		http.MethodPut, http.MethodDelete:
+	default:
+		if req.Header.Get("Idempotency-Key") == "" {
+			return false
+		}
+	}
+	return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+}
+
+func rewind(req *http.Request) (*http.Request, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return req, nil
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, err
+	}
+	req = req.Clone(req.Context())
+	req.Body = body
+	return req, nil
+}
+
+// retryAfter parses delay-seconds or an HTTP date.
+func retryAfter(v string, now time.Time) (time.Duration, bool) {
+	if v == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(v); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		d := t.Sub(now)
+		if d < 0 {
+			d = 0
+		}
+		return d, true
+	}
+	return 0, false
+}
+
+func sleep(ctx context.Context, clk ports.Clock, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	t := clk.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C():
+		return nil
+	}
+}
@@ -0,0 +1,382 @@
+package httpx
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aatuh/api-toolkit/ports"
+)
+
+// BindError reports a request that could not be bound into its target
+// type. It renders as a 400 Problem.
+type BindError struct {
+	Source string // "body", "path" or "query"
+	Field  string // parameter or JSON field name, when known
+	// Line and Column locate the problem in a JSON body, 1-based; zero
+	// when unknown.
+	Line, Column int
+	Err          error
+}
+
+func (e *BindError) Error() string {
+	var msg string
+	switch {
+	case e.Field != "" && e.Source == "body":
+		msg = fmt.Sprintf("invalid body field %q: %v", e.Field, e.Err)
+	case e.Field != "":
+		msg = fmt.Sprintf("invalid %s parameter %q: %v", e.Source, e.Field, e.Err)
+	default:
+		msg = fmt.Sprintf("invalid %s: %v", e.Source, e.Err)
+	}
+	if e.Line > 0 {
+		msg += fmt.Sprintf(" (line %d, column %d)", e.Line, e.Column)
+	}
+	return msg
+}
+
+func (e *BindError) Unwrap() error { return e.Err }
+
+// Problem implements ProblemError.
+func (e *BindError) Problem() Problem {
+	p := Problem{
+		Title:  http.StatusText(http.StatusBadRequest),
+		Status: http.StatusBadRequest,
+		Detail: e.Error(),
+	}
+	if e.Field != "" {
+		p.With("field", e.Field)
+	}
+	if e.Line > 0 {
+		p.With("line", e.Line).With("column", e.Column)
+	}
+	return p
+}
+
+// DefaultMaxJSONBytes caps the body read by DecodeJSON, since the whole
+// body is held in memory to report error positions.
+var DefaultMaxJSONBytes int64 = 1 << 20
+
+// DecodeJSON strictly decodes the request body into dst: unknown fields
+// and trailing data are rejected. An empty body leaves dst untouched.
+// Bodies over DefaultMaxJSONBytes surface as *http.MaxBytesError; other
+// failures are *BindError naming the field and, where known, the line
+// and column.
+func DecodeJSON(r *http.Request, dst any) error {
+	return DecodeJSONLimit(r, dst, 0)
+}
+
+// DecodeJSONLimit is DecodeJSON that rejects bodies larger than maxBytes
+// with *http.MaxBytesError, which maps to 413. Zero or less means
+// DefaultMaxJSONBytes.
+func DecodeJSONLimit(r *http.Request, dst any, maxBytes int64) error {
+	if r.Body == nil || r.Body == http.NoBody {
+		return nil
+	}
+	if maxBytes <= 0 {
+		maxBytes = DefaultMaxJSONBytes
+	}
+	if r.ContentLength > maxBytes {
+		return &http.MaxBytesError{Limit: maxBytes}
+	}
+	data, err := io.ReadAll(io.LimitReader(r.Body, maxBytes+1))
+	if err != nil {
+		return bodyError(err, nil)
+	}
+	if int64(len(data)) > maxBytes {
+		return &http.MaxBytesError{Limit: maxBytes}
+	}
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(dst); err != nil {
+		return bodyError(err, data)
+	}
+	if _, err := dec.Token(); !errors.Is(err, io.EOF) {
+		off := dec.InputOffset()
+		for off < int64(len(data)) && isSpace(data[off]) {
+			off++
+		}
+		be := &BindError{Source: "body", Err: errors.New("unexpected data after JSON value")}
+		be.Line, be.Column = position(data, off)
+		return be
+	}
+	return nil
+}
+
+func bodyError(err error, data []byte) error {
+	var maxErr *http.MaxBytesError
+	if errors.As(err, &maxErr) {
+		return err
+	}
+	be := &BindError{Source: "body", Err: err}
+	var typeErr *json.UnmarshalTypeError
+	var syntaxErr *json.SyntaxError
+	switch {
+	case errors.As(err, &typeErr):
+		be.Field, be.Err = typeErr.Field, fmt.Errorf("expected %s", typeErr.Type)
+		be.Line, be.Column = position(data, valueStart(data, typeErr.Offset))
+	case errors.As(err, &syntaxErr):
+		be.Err = errors.New("malformed JSON")
+		be.Line, be.Column = position(data, syntaxErr.Offset-1)
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		be.Err = errors.New("malformed JSON")
+		be.Line, be.Column = position(data, int64(len(data)))
+	default:
+		if f, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+			be.Field, be.Err = strings.Trim(f, `"`), errors.New("unknown field")
+			be.Line, be.Column = position(data, keyOffset(data, be.Field))
+		}
+	}
+	return be
+}
+
+// position converts a byte offset in data to a 1-based line and column.
+// It returns zeros when data is nil or off is unknown.
+func position(data []byte, off int64) (line, col int) {
+	if data == nil || off < 0 {
+		return 0, 0
+	}
+	off = min(off, int64(len(data)))
+	head := data[:off]
+	return bytes.Count(head, []byte{'\n'}) + 1, int(off) - bytes.LastIndexByte(head, '\n')
+}
+
+// valueStart finds the start of the value ending at end, the offset
+// json.UnmarshalTypeError reports.
+func valueStart(data []byte, end int64) int64 {
+	end = min(end, int64(len(data)))
+	if end <= 0 {
+		return end
+	}
+	i := end - 1
+	switch c := data[i]; c {
+	case '"':
+		for i--; i >= 0; i-- {
+			if data[i] == '"' && !escaped(data, i) {
+				return i
+			}
+		}
+		return -1
+	case '}', ']':
+		open := byte('{')
+		if c == ']' {
+			open = '['
+		}
+		depth := 0
+		for ; i >= 0; i-- {
+			switch data[i] {
+			case c:
+				depth++
+			case open:
+				if depth--; depth == 0 {
+					return i
+				}
+			}
+		}
+		return -1
+	}
+	for i > 0 && !isSpace(data[i-1]) && !bytes.ContainsRune([]byte(":,["), rune(data[i-1])) {
+		i--
+	}
+	return i
+}
+
+// escaped reports whether data[i] is preceded by an odd number of
+// backslashes.
+func escaped(data []byte, i int64) bool {
+	n := 0
+	for j := i - 1; j >= 0 && data[j] == '\\'; j-- {
+		n++
+	}
+	return n%2 == 1
+}
+
+// keyOffset finds the first occurrence of name as an object key. The
+// decoder does not report where unknown fields are.
+func keyOffset(data []byte, name string) int64 {
+	key, _ := json.Marshal(name)
+	for from := 0; ; {
+		i := bytes.Index(data[from:], key)
+		if i < 0 {
+			return -1
+		}
+		i += from
+		rest := bytes.TrimLeft(data[i+len(key):], " \t\r\n")
+		if len(rest) > 0 && rest[0] == ':' {
+			return int64(i)
+		}
+		from = i + len(key)
+	}
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\r' || c == '\n'
+}
+
+// BindParams fills fields of the struct pointed to by dst from path and
+// query parameters, using `path:"name"` and `query:"name"` tags. Path
+// values come from params, or r.PathValue when params is nil. Supported
+// field types are strings, bools, numbers, time.Duration,
+// encoding.TextUnmarshaler implementations, and pointers or slices of
+// those; slices collect repeated query parameters.
+func BindParams(r *http.Request, params ports.URLParamExtractor, dst any) error {
+	rv := reflect.ValueOf(dst)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return errors.New("httpx: BindParams requires a non-nil pointer")
+	}
+	rv = rv.Elem()
+	if rv.Kind() != reflect.Struct {
+		return nil
+	}
+	var query map[string][]string
+	for _, f := range paramFields(rv.Type()) {
+		var vals []string
+		switch f.source {
+		case "path":
+			var v string
+			if params != nil {
+				v = params.URLParam(r, f.name)
+			} else {
+				v = r.PathValue(f.name)
+			}
+			if v == "" {
+				continue
+			}
+			vals = []string{v}
+		case "query":
+			if query == nil {
+				query = r.URL.Query()
+			}
+			vals = query[f.name]
+			if len(vals) == 0 {
+				continue
+			}
+		}
+		if err := setField(rv.FieldByIndex(f.index), vals); err != nil {
+			return &BindError{Source: f.source, Field: f.name, Err: err}
+		}
+	}
+	return nil
+}
+
+type paramField struct {
+	index  []int
+	source string
+	name   string
+}
+
+var paramCache sync.Map // reflect.Type -> []paramField
+
+func paramFields(t reflect.Type) []paramField {
+	if v, ok := paramCache.Load(t); ok {
+		return v.([]paramField)
+	}
+	var out []paramField
+	var walk func(t reflect.Type, index []int)
+	walk = func(t reflect.Type, index []int) {
+		for i := 0; i < t.NumField(); i++ {
+			sf := t.Field(i)
+			idx := append(append([]int(nil), index...), i)
+			if sf.Anonymous && sf.Type.Kind() == reflect.Struct {
+				walk(sf.Type, idx)
+				continue
+			}
+			if !sf.IsExported() {
+				continue
+			}
+			for _, src := range []string{"path", "query"} {
+				if name, _, _ := strings.Cut(sf.Tag.Get(src), ","); name != "" && name != "-" {
+					out = append(out, paramField{index: idx, source: src, name: name})
+				}
+			}
+		}
+	}
+	walk(t, nil)
+	paramCache.Store(t, out)
+	return out
+}
+
+var (
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	durationType        = reflect.TypeOf(time.Duration(0))
+)
+
+func setField(v reflect.Value, vals []string) error {
+	if v.Kind() == reflect.Slice && !v.Type().Implements(textUnmarshalerType) &&
+		v.Type().Elem().Kind() != reflect.Uint8 {
+		s := reflect.MakeSlice(v.Type(), len(vals), len(vals))
+		for i, raw := range vals {
+			if err := setScalar(s.Index(i), raw); err != nil {
+				return err
+			}
+		}
+		v.Set(s)
+		return nil
+	}
+	return setScalar(v, vals[len(vals)-1])
+}
+
+func setScalar(v reflect.Value, raw string) error {
+	if v.Kind() == reflect.Pointer {
+		p := reflect.New(v.Type().Elem())
+		if err := setScalar(p.Elem(), raw); err != nil {
+			return err
+		}
+		v.Set(p)
+		return nil
+	}
+	if v.CanAddr() && v.Addr().Type().Implements(textUnmarshalerType) {
+		return v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(raw))
+	}
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return errors.New("invalid duration")
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(raw)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return errors.New("invalid boolean")
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(raw, 10, v.Type().Bits())
+		if err != nil {
+			return errors.New("invalid integer")
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(raw, 10, v.Type().Bits())
+		if err != nil {
+			return errors.New("invalid unsigned integer")
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(raw, v.Type().Bits())
+		if err != nil {
+			return errors.New("invalid number")
+		}
+		v.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
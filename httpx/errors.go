@@ -0,0 +1,217 @@
+package httpx
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+
+	"github.com/aatuh/api-toolkit/logctx"
+	"github.com/aatuh/api-toolkit/txpostgres"
+	"github.com/aatuh/api-toolkit/validation"
+)
+
+// ProblemError is implemented by errors that carry their own Problem. It is
+// consulted before any registered mapping.
+type ProblemError interface {
+	error
+	Problem() Problem
+}
+
+// ErrorMapper maps errors to Problem templates. Mappings registered later
+// take precedence, so application rules override the built-in ones. It is
+// safe for concurrent use.
+type ErrorMapper struct {
+	mu    sync.RWMutex
+	rules []func(error) (Problem, bool)
+}
+
+// NewErrorMapper returns a mapper preloaded with the toolkit defaults:
+// validation errors, txpostgres no-rows and common Postgres error codes,
+// request body limits and context deadlines.
+func NewErrorMapper() *ErrorMapper {
+	m := &ErrorMapper{}
+	registerDefaults(m)
+	return m
+}
+
+// DefaultErrorMapper is used by WriteError and the package-level Register
+// helpers.
+var DefaultErrorMapper = NewErrorMapper()
+
+// Register maps errors matching target via errors.Is to p. When p.Detail
+// is empty and p.Status is below 500, the error message becomes the
+// detail; server errors never expose it.
+func (m *ErrorMapper) Register(target error, p Problem) {
+	m.RegisterFunc(func(err error) (Problem, bool) {
+		if !errors.Is(err, target) {
+			return Problem{}, false
+		}
+		return fromTemplate(p, err), true
+	})
+}
+
+// RegisterFunc adds a custom matcher. fn returns the Problem to render and
+// whether it matched.
+func (m *ErrorMapper) RegisterFunc(fn func(error) (Problem, bool)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.rules = append(m.rules, fn)
+}
+
+// RegisterType maps errors of type T, matched via errors.As, to p. Detail
+// follows the same rules as ErrorMapper.Register.
+func RegisterType[T error](m *ErrorMapper, p Problem) {
+	m.RegisterFunc(func(err error) (Problem, bool) {
+		var target T
+		if !errors.As(err, &target) {
+			return Problem{}, false
+		}
+		return fromTemplate(p, target), true
+	})
+}
+
+// RegisterError registers a sentinel on DefaultErrorMapper.
+func RegisterError(target error, p Problem) {
+	DefaultErrorMapper.Register(target, p)
+}
+
+// Map returns the Problem for err and whether any mapping matched.
+func (m *ErrorMapper) Map(err error) (Problem, bool) {
+	if err == nil {
+		return Problem{}, false
+	}
+	var pe ProblemError
+	if errors.As(err, &pe) {
+		return fromTemplate(pe.Problem(), nil), true
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for i := len(m.rules) - 1; i >= 0; i-- {
+		if p, ok := m.rules[i](err); ok {
+			return p, true
+		}
+	}
+	return Problem{}, false
+}
+
+// Resolve is Map with a sanitized 500 fallback for unmapped errors.
+func (m *ErrorMapper) Resolve(err error) Problem {
+	if p, ok := m.Map(err); ok {
+		return p
+	}
+	return internalProblem()
+}
+
+// Write renders err as a Problem (see WriteProblemFor). Server errors
+// are logged with the original error through the request logger, since
+// clients only see a sanitized message.
+func (m *ErrorMapper) Write(w http.ResponseWriter, r *http.Request, err error) {
+	p := m.Resolve(err)
+	if p.Status >= http.StatusInternalServerError {
+		logctx.FromContext(r.Context()).Error("request failed",
+			"status", p.Status, "error", err)
+	}
+	WriteProblemFor(w, r, p.Status, p)
+}
+
+// WriteError renders err using DefaultErrorMapper.
+func WriteError(w http.ResponseWriter, r *http.Request, err error) {
+	DefaultErrorMapper.Write(w, r, err)
+}
+
+func internalProblem() Problem {
+	return Problem{
+		Title:    http.StatusText(http.StatusInternalServerError),
+		Status:   http.StatusInternalServerError,
+		Detail:   "internal server error",
+		DetailID: "problem.internal",
+	}
+}
+
+// fromTemplate copies p so templates are never mutated, defaulting the
+// status and title.
+func fromTemplate(p Problem, err error) Problem {
+	if p.Status <= 0 {
+		p.Status = http.StatusInternalServerError
+	}
+	if p.Title == "" {
+		p.Title = http.StatusText(p.Status)
+	}
+	if p.Detail == "" && err != nil && p.Status < http.StatusInternalServerError {
+		p.Detail = err.Error()
+	}
+	if p.Ext != nil {
+		ext := make(map[string]any, len(p.Ext))
+		for k, v := range p.Ext {
+			ext[k] = v
+		}
+		p.Ext = ext
+	}
+	return p
+}
+
+// Postgres SQLSTATE codes mapped by default.
+var pgStatus = map[string]Problem{
+	"23505": {Status: http.StatusConflict, Detail: "resource already exists", DetailID: "problem.already_exists"},
+	"23503": {Status: http.StatusConflict, Detail: "referenced resource does not exist or is still in use", DetailID: "problem.reference"},
+	"23502": {Status: http.StatusUnprocessableEntity, Detail: "a required value is missing", DetailID: "problem.missing_value"},
+	"23514": {Status: http.StatusUnprocessableEntity, Detail: "a value violates a constraint", DetailID: "problem.constraint"},
+	"40001": {Status: http.StatusConflict, Detail: "concurrent update conflict; retry the request", DetailID: "problem.concurrent_update"},
+	"40P01": {Status: http.StatusConflict, Detail: "concurrent update conflict; retry the request", DetailID: "problem.concurrent_update"},
+}
+
+func registerDefaults(m *ErrorMapper) {
+	m.Register(context.DeadlineExceeded, Problem{
+		Status:   http.StatusGatewayTimeout,
+		Detail:   "request timed out",
+		DetailID: "problem.timeout",
+	})
+	RegisterType[*http.MaxBytesError](m, Problem{
+		Status:   http.StatusRequestEntityTooLarge,
+		Detail:   "request body too large",
+		DetailID: "problem.body_too_large",
+	})
+	m.RegisterFunc(func(err error) (Problem, bool) {
+		pgErr, ok := txpostgres.AsPgError(err)
+		if !ok {
+			return Problem{}, false
+		}
+		p, ok := pgStatus[pgErr.Code]
+		if !ok {
+			return Problem{}, false
+		}
+		return fromTemplate(p, nil), true
+	})
+	m.RegisterFunc(func(err error) (Problem, bool) {
+		if !txpostgres.IsNoRows(err) {
+			return Problem{}, false
+		}
+		return fromTemplate(Problem{
+			Status:   http.StatusNotFound,
+			Detail:   "resource not found",
+			DetailID: "problem.not_found",
+		}, nil), true
+	})
+	m.RegisterFunc(func(err error) (Problem, bool) {
+		var fields []validation.ValidationError
+		var ves validation.ValidationErrors
+		var ve validation.ValidationError
+		switch {
+		case errors.As(err, &ves):
+			fields = ves.Errors
+		case errors.As(err, &ve):
+			fields = []validation.ValidationError{ve}
+		default:
+			return Problem{}, false
+		}
+		p := fromTemplate(Problem{
+			Status:  http.StatusBadRequest,
+			Title:   "Validation failed",
+			TitleID: "problem.validation_failed",
+			Detail:  err.Error(),
+		}, nil)
+		p.With("errors", fields)
+		return p, true
+	})
+}
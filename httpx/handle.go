@@ -0,0 +1,121 @@
+package httpx
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"reflect"
+
+	"github.com/aatuh/api-toolkit/codec"
+	"github.com/aatuh/api-toolkit/i18n"
+	"github.com/aatuh/api-toolkit/logctx"
+	"github.com/aatuh/api-toolkit/ports"
+)
+
+// HandlerOptions configures HandleWith.
+type HandlerOptions struct {
+	// Validator validates the bound request. Nil skips validation.
+	Validator ports.Validator
+	// Params extracts path parameters. Nil uses r.PathValue, which chi
+	// and net/http's ServeMux both populate.
+	Params ports.URLParamExtractor
+	// Status is the success status code. Defaults to 200; with 204 the
+	// response value is not written.
+	Status int
+	// Errors maps failures to Problems. Defaults to DefaultErrorMapper.
+	Errors *ErrorMapper
+	// Encoders negotiates the response format. Defaults to codec.Default.
+	Encoders *codec.Registry
+}
+
+// Handle adapts a typed function into an http.HandlerFunc. The request
+// body is strictly decoded as JSON into Req, then `path` and `query`
+// tagged fields are bound (see BindParams), the result is validated with
+// v, and fn's response is encoded in the format negotiated from Accept
+// (406 when none is acceptable). Any failure is written as a Problem.
+func Handle[Req, Resp any](v ports.Validator, fn func(ctx context.Context, req Req) (Resp, error)) http.HandlerFunc {
+	return HandleWith(HandlerOptions{Validator: v}, fn)
+}
+
+// HandleWith is Handle with explicit options.
+func HandleWith[Req, Resp any](opts HandlerOptions, fn func(ctx context.Context, req Req) (Resp, error)) http.HandlerFunc {
+	if opts.Status == 0 {
+		opts.Status = http.StatusOK
+	}
+	if opts.Errors == nil {
+		opts.Errors = DefaultErrorMapper
+	}
+	if opts.Encoders == nil {
+		opts.Encoders = codec.Default
+	}
+	isStruct := reflect.TypeFor[Req]().Kind() == reflect.Struct
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		if i18n.LanguageFromContext(ctx) == "" {
+			// Validation messages follow Accept-Language even without
+			// i18n.Middleware.
+			ctx = i18n.WithLanguage(ctx, i18n.RequestLanguage(r, nil))
+		}
+		enc, ok := opts.Encoders.Negotiate(r)
+		if !ok {
+			WriteNotAcceptable(w, r, opts.Encoders.MediaTypes())
+			return
+		}
+		var req Req
+		if err := DecodeJSON(r, &req); err != nil {
+			opts.Errors.Write(w, r, err)
+			return
+		}
+		if isStruct {
+			if err := BindParams(r, opts.Params, &req); err != nil {
+				opts.Errors.Write(w, r, err)
+				return
+			}
+			if opts.Validator != nil {
+				if err := opts.Validator.ValidateStruct(ctx, &req); err != nil {
+					opts.Errors.Write(w, r, err)
+					return
+				}
+			}
+		}
+		resp, err := fn(ctx, req)
+		if err != nil {
+			opts.Errors.Write(w, r, err)
+			return
+		}
+		if opts.Status == http.StatusNoContent {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		WriteEncoded(w, r, opts.Status, enc, resp)
+	}
+}
+
+// WriteNotAcceptable writes a 406 Problem listing the available media
+// types.
+func WriteNotAcceptable(w http.ResponseWriter, r *http.Request, available []string) {
+	p := Problem{
+		Title:    http.StatusText(http.StatusNotAcceptable),
+		Detail:   "none of the accepted media types can be produced",
+		DetailID: "problem.not_acceptable",
+	}
+	p.With("available", available)
+	WriteProblemFor(w, r, http.StatusNotAcceptable, p)
+}
+
+// WriteEncoded encodes v with enc into a buffer and writes it with code.
+// Encoding failures become a 500 Problem instead of a truncated body.
+func WriteEncoded(w http.ResponseWriter, r *http.Request, code int, enc codec.Encoder, v any) {
+	var buf bytes.Buffer
+	if err := enc.Encode(&buf, v); err != nil {
+		logctx.FromContext(r.Context()).Error("response encoding failed",
+			"media_type", enc.MediaType(), "error", err)
+		WriteProblemFor(w, r, http.StatusInternalServerError, internalProblem())
+		return
+	}
+	h := w.Header()
+	h.Set("Content-Type", enc.MediaType())
+	h.Add("Vary", "Accept")
+	w.WriteHeader(code)
+	_, _ = w.Write(buf.Bytes())
+}
@@ -2,7 +2,17 @@ package httpx
 
 import (
 	"encoding/json"
+	"encoding/xml"
+	"fmt"
 	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+
+	"github.com/aatuh/api-toolkit/chi"
+	"github.com/aatuh/api-toolkit/codec"
+	"github.com/aatuh/api-toolkit/i18n"
+	"github.com/aatuh/api-toolkit/middleware/trace"
 )
 
 // Problem represents an RFC 7807 problem+json response body.
@@ -14,6 +24,12 @@ type Problem struct {
 	Detail   string         `json:"detail,omitempty"`
 	Instance string         `json:"instance,omitempty"`
 	Ext      map[string]any `json:"-"`
+
+	// TitleID and DetailID name i18n messages that replace Title and
+	// Detail when the problem is written for a request. Titles equal to
+	// the standard status text are localized without an ID.
+	TitleID  string `json:"-"`
+	DetailID string `json:"-"`
 }
 
 // With adds an extension field to the problem payload.
@@ -28,18 +44,151 @@ func (p *Problem) With(key string, value any) *Problem {
 	return p
 }
 
+// Problem media types.
+const (
+	ProblemJSON = "application/problem+json"
+	ProblemXML  = "application/problem+xml"
+)
+
 // WriteProblem writes a problem+json response with the provided status code.
 // It merges extension fields after the standard members, per RFC 7807.
 func WriteProblem(w http.ResponseWriter, status int, p Problem) {
+	writeProblem(w, status, p, codec.JSON{})
+}
+
+// WriteProblemFor is the request-aware WriteProblem. It correlates the
+// problem with the request (see Correlate) and negotiates the format:
+// clients that prefer XML (application/problem+xml or application/xml)
+// get the RFC 7807 XML form, everyone else gets problem+json. A problem
+// is never refused with 406. Title and detail are localized (see
+// Localize) and Content-Language names the language used.
+func WriteProblemFor(w http.ResponseWriter, r *http.Request, status int, p Problem) {
+	var enc codec.Encoder = codec.JSON{}
+	if r != nil {
+		p = Correlate(r, Localize(r, status, p))
+		mt, _ := codec.Negotiate(r.Header.Get("Accept"), problemOffers)
+		if mt == ProblemXML || mt == "application/xml" {
+			enc = codec.XML{}
+		}
+		if codec.WantsPretty(r) {
+			enc = enc.(codec.Prettifier).Pretty()
+		}
+		w.Header().Add("Vary", "Accept")
+		i18n.SetContentLanguage(w.Header(), i18n.RequestLanguage(r, nil))
+	}
+	writeProblem(w, status, p, enc)
+}
+
+// Localize translates p's title and detail into the request language (see
+// i18n.RequestLanguage), keeping the English text when no message exists.
+func Localize(r *http.Request, status int, p Problem) Problem {
+	lang := i18n.RequestLanguage(r, nil)
+	titleID := p.TitleID
+	if titleID == "" && (p.Title == "" || p.Title == http.StatusText(status)) {
+		titleID = "http.status." + strconv.Itoa(status)
+	}
+	if msg, ok := i18n.Default.Lookup(lang, titleID); ok {
+		p.Title = msg
+	}
+	if p.DetailID != "" {
+		if msg, ok := i18n.Default.Lookup(lang, p.DetailID); ok {
+			p.Detail = msg
+		}
+	}
+	return p
+}
+
+// Correlate ties p to r so clients can quote something searchable in the
+// logs. Unless already set, instance becomes the request path with the
+// request ID as fragment ("/users/42#<request id>"), and the request_id
+// and trace_id extensions are added when known. p.Ext is copied, not
+// mutated.
+func Correlate(r *http.Request, p Problem) Problem {
+	rid := chi.GetRequestID(r)
+	traceID := trace.GetTraceID(r)
+	if p.Instance == "" && r.URL != nil {
+		p.Instance = r.URL.Path
+		if rid != "" {
+			p.Instance += "#" + (&url.URL{Fragment: rid}).EscapedFragment()
+		}
+	}
+	ext := make(map[string]any, len(p.Ext)+2)
+	for k, v := range p.Ext {
+		ext[k] = v
+	}
+	if _, ok := ext["request_id"]; !ok && rid != "" {
+		ext["request_id"] = rid
+	}
+	if _, ok := ext["trace_id"]; !ok && traceID != "" {
+		ext["trace_id"] = traceID
+	}
+	if len(ext) > 0 {
+		p.Ext = ext
+	}
+	return p
+}
+
+var problemOffers = []string{ProblemJSON, ProblemXML, "application/json", "application/xml"}
+
+func writeProblem(w http.ResponseWriter, status int, p Problem, enc codec.Encoder) {
 	if status <= 0 {
 		status = http.StatusInternalServerError
 	}
 	p.Status = status
 
-	w.Header().Set("Content-Type", "application/problem+json")
+	contentType := ProblemJSON
+	var body any = p.fields()
+	if _, ok := enc.(codec.XML); ok {
+		contentType, body = ProblemXML, p
+	}
+	w.Header().Set("Content-Type", contentType)
 	w.WriteHeader(status)
+	_ = enc.Encode(w, body)
+}
+
+// MarshalJSON encodes the problem with its extension members inlined.
+func (p Problem) MarshalJSON() ([]byte, error) {
+	return json.Marshal(p.fields())
+}
+
+// UnmarshalJSON decodes a problem document, collecting members other than
+// the standard ones into Ext.
+func (p *Problem) UnmarshalJSON(data []byte) error {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	var out Problem
+	for k, v := range raw {
+		var err error
+		switch k {
+		case "type":
+			err = json.Unmarshal(v, &out.Type)
+		case "title":
+			err = json.Unmarshal(v, &out.Title)
+		case "status":
+			err = json.Unmarshal(v, &out.Status)
+		case "detail":
+			err = json.Unmarshal(v, &out.Detail)
+		case "instance":
+			err = json.Unmarshal(v, &out.Instance)
+		default:
+			var ext any
+			if err = json.Unmarshal(v, &ext); err == nil {
+				out.With(k, ext)
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("problem member %q: %w", k, err)
+		}
+	}
+	*p = out
+	return nil
+}
 
-	// Marshal with extensions by composing a map to preserve standard fields.
+// fields composes the members, merging extension fields after the
+// standard ones; extensions cannot override standard members.
+func (p Problem) fields() map[string]any {
 	out := map[string]any{}
 	if p.Type != "" {
 		out["type"] = p.Type
@@ -57,13 +206,50 @@ func WriteProblem(w http.ResponseWriter, status int, p Problem) {
 		out["instance"] = p.Instance
 	}
 	for k, v := range p.Ext {
-		if k == "type" || k == "title" || k == "status" ||
-			k == "detail" || k == "instance" {
+		if memberRank(k) < len(standardMembers) {
 			continue
 		}
 		out[k] = v
 	}
-	_ = json.NewEncoder(w).Encode(out)
+	return out
+}
+
+var standardMembers = []string{"type", "title", "status", "detail", "instance"}
+
+// MarshalXML renders the RFC 7807 Appendix A form: a <problem> element in
+// the urn:ietf:rfc:7807 namespace with one child per member.
+func (p Problem) MarshalXML(e *xml.Encoder, _ xml.StartElement) error {
+	start := xml.StartElement{Name: xml.Name{Space: "urn:ietf:rfc:7807", Local: "problem"}}
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+	fields := p.fields()
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		ri, rj := memberRank(keys[i]), memberRank(keys[j])
+		if ri != rj {
+			return ri < rj
+		}
+		return keys[i] < keys[j]
+	})
+	for _, k := range keys {
+		if err := codec.EncodeXMLElement(e, k, fields[k]); err != nil {
+			return err
+		}
+	}
+	return e.EncodeToken(start.End())
+}
+
+func memberRank(k string) int {
+	for i, m := range standardMembers {
+		if m == k {
+			return i
+		}
+	}
+	return len(standardMembers)
 }
 
 // WriteSimpleProblem is a convenience for common cases.
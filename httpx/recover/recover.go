@@ -1,21 +1,33 @@
 package recover
 
 import (
+	"fmt"
 	"net/http"
+	"runtime/debug"
 
 	"github.com/aatuh/api-toolkit/httpx"
+	"github.com/aatuh/api-toolkit/logctx"
 )
 
-// Middleware converts panics into RFC-7807 problem+json responses.
-// It intentionally does not leak panic values to clients.
+// Middleware converts panics into RFC-7807 problem responses.
+// It intentionally does not leak panic values to clients; they are logged
+// with the stack through the request logger, and the problem carries the
+// request and trace IDs (see httpx.Correlate) to find that log line.
+// http.ErrAbortHandler is re-raised so net/http can abort the connection.
 func Middleware() func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			defer func() {
 				if rec := recover(); rec != nil {
-					httpx.WriteProblem(w, http.StatusInternalServerError, httpx.Problem{
-						Title:  http.StatusText(http.StatusInternalServerError),
-						Detail: "internal server error",
+					if rec == http.ErrAbortHandler {
+						panic(rec)
+					}
+					logctx.FromContext(r.Context()).Error("panic recovered",
+						"panic", fmt.Sprint(rec), "stack", string(debug.Stack()))
+					httpx.WriteProblemFor(w, r, http.StatusInternalServerError, httpx.Problem{
+						Title:    http.StatusText(http.StatusInternalServerError),
+						Detail:   "internal server error",
+						DetailID: "problem.internal",
 					})
 				}
 			}()
@@ -0,0 +1,70 @@
+package i18n
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+type ctxKey struct{}
+
+// WithLanguage stores lang in ctx.
+func WithLanguage(ctx context.Context, lang string) context.Context {
+	return context.WithValue(ctx, ctxKey{}, lang)
+}
+
+// LanguageFromContext returns the language stored in ctx, or "" so callers
+// get the bundle's fallback.
+func LanguageFromContext(ctx context.Context) string {
+	lang, _ := ctx.Value(ctxKey{}).(string)
+	return lang
+}
+
+// RequestLanguage returns the language stored in r's context, negotiating
+// Accept-Language against b (Default when nil) if Middleware has not run.
+func RequestLanguage(r *http.Request, b *Bundle) string {
+	if lang := LanguageFromContext(r.Context()); lang != "" {
+		return lang
+	}
+	if b == nil {
+		b = Default
+	}
+	return b.Match(r.Header.Get("Accept-Language"))
+}
+
+// Middleware negotiates Accept-Language against b (Default when nil) and
+// stores the result in the request context for T and the validators.
+// Since any response may then carry localized text, it adds
+// Vary: Accept-Language so shared caches keep languages apart.
+func Middleware(b *Bundle) func(http.Handler) http.Handler {
+	if b == nil {
+		b = Default
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			lang := b.Match(r.Header.Get("Accept-Language"))
+			varyAcceptLanguage(w.Header())
+			next.ServeHTTP(w, r.WithContext(WithLanguage(r.Context(), lang)))
+		})
+	}
+}
+
+// SetContentLanguage marks a response as localized into lang: it sets
+// Content-Language and adds Vary: Accept-Language.
+func SetContentLanguage(h http.Header, lang string) {
+	if lang != "" {
+		h.Set("Content-Language", lang)
+	}
+	varyAcceptLanguage(h)
+}
+
+func varyAcceptLanguage(h http.Header) {
+	for _, v := range h.Values("Vary") {
+		for _, f := range strings.Split(v, ",") {
+			if f = strings.TrimSpace(f); f == "*" || strings.EqualFold(f, "Accept-Language") {
+				return
+			}
+		}
+	}
+	h.Add("Vary", "Accept-Language")
+}
@@ -0,0 +1,233 @@
+package i18n
+
+import (
+	"context"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// FallbackLanguage is the language used when nothing better matches.
+const FallbackLanguage = "en"
+
+//go:embed locales/*.json
+var builtin embed.FS
+
+// Bundle holds message catalogs keyed by language tag and message ID.
+// Messages may contain {name} placeholders filled from key/value args.
+type Bundle struct {
+	mu       sync.RWMutex
+	fallback string
+	catalogs map[string]map[string]string
+}
+
+// NewBundle returns an empty bundle falling back to fallback (default
+// FallbackLanguage).
+func NewBundle(fallback string) *Bundle {
+	if fallback == "" {
+		fallback = FallbackLanguage
+	}
+	return &Bundle{
+		fallback: normalize(fallback),
+		catalogs: map[string]map[string]string{},
+	}
+}
+
+// Default carries the toolkit's own messages (en, de, fr, fi). Add
+// application messages to it with AddMessages or LoadFS.
+var Default = func() *Bundle {
+	b := NewBundle(FallbackLanguage)
+	if err := b.LoadFS(builtin, "locales/*.json"); err != nil {
+		panic(err)
+	}
+	return b
+}()
+
+// AddMessages merges msgs into the catalog for lang; later additions win.
+func (b *Bundle) AddMessages(lang string, msgs map[string]string) {
+	lang = normalize(lang)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	cat := b.catalogs[lang]
+	if cat == nil {
+		cat = make(map[string]string, len(msgs))
+		b.catalogs[lang] = cat
+	}
+	for id, msg := range msgs {
+		cat[id] = msg
+	}
+}
+
+// LoadFS loads every file matching pattern as a flat JSON object of
+// message ID to text. The file name without extension is the language
+// tag, e.g. "locales/de.json" or "locales/pt-BR.json".
+func (b *Bundle) LoadFS(fsys fs.FS, pattern string) error {
+	files, err := fs.Glob(fsys, pattern)
+	if err != nil {
+		return err
+	}
+	for _, name := range files {
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return err
+		}
+		var msgs map[string]string
+		if err := json.Unmarshal(data, &msgs); err != nil {
+			return fmt.Errorf("i18n: %s: %w", name, err)
+		}
+		base := path.Base(name)
+		b.AddMessages(strings.TrimSuffix(base, path.Ext(base)), msgs)
+	}
+	return nil
+}
+
+// Languages lists the languages with a catalog, sorted.
+func (b *Bundle) Languages() []string {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	out := make([]string, 0, len(b.catalogs))
+	for lang := range b.catalogs {
+		out = append(out, lang)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Fallback returns the fallback language.
+func (b *Bundle) Fallback() string { return b.fallback }
+
+// Lookup returns the message for id in lang, trying the base language
+// ("de" for "de-AT") and then the fallback. It reports false when no
+// catalog has the message.
+func (b *Bundle) Lookup(lang, id string) (string, bool) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for _, l := range b.chain(normalize(lang)) {
+		if msg, ok := b.catalogs[l][id]; ok {
+			return msg, true
+		}
+	}
+	return "", false
+}
+
+// Translate returns the message for id in lang with {name} placeholders
+// replaced from kv pairs. Unknown IDs are returned as-is.
+func (b *Bundle) Translate(lang, id string, kv ...any) string {
+	msg, ok := b.Lookup(lang, id)
+	if !ok {
+		msg = id
+	}
+	return format(msg, kv)
+}
+
+// T translates id into the language stored in ctx.
+func (b *Bundle) T(ctx context.Context, id string, kv ...any) string {
+	return b.Translate(LanguageFromContext(ctx), id, kv...)
+}
+
+// T translates id with the Default bundle into the language in ctx.
+func T(ctx context.Context, id string, kv ...any) string {
+	return Default.T(ctx, id, kv...)
+}
+
+func (b *Bundle) chain(lang string) []string {
+	out := make([]string, 0, 3)
+	if lang != "" {
+		out = append(out, lang)
+		if base, _, ok := strings.Cut(lang, "-"); ok {
+			out = append(out, base)
+		}
+	}
+	return append(out, b.fallback)
+}
+
+// Match negotiates an Accept-Language header value against the bundle's
+// languages. Ranges are tried by descending q-value; a range matches a
+// catalog with the same tag, the same base language, or (for "*") the
+// fallback. The fallback is returned when nothing matches.
+func (b *Bundle) Match(acceptLanguage string) string {
+	langs := b.Languages()
+	for _, want := range parseAcceptLanguage(acceptLanguage) {
+		if want == "*" {
+			return b.fallback
+		}
+		for _, l := range langs {
+			if l == want {
+				return l
+			}
+		}
+		wantBase, _, _ := strings.Cut(want, "-")
+		for _, l := range langs {
+			if base, _, _ := strings.Cut(l, "-"); base == wantBase {
+				return l
+			}
+		}
+	}
+	return b.fallback
+}
+
+// parseAcceptLanguage returns normalized tags ordered by q-value, keeping
+// header order for ties and dropping q=0.
+func parseAcceptLanguage(header string) []string {
+	type entry struct {
+		tag string
+		q   float64
+	}
+	var entries []entry
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(part, ";")
+		tag = normalize(tag)
+		if tag == "" {
+			continue
+		}
+		q := 1.0
+		if k, v, ok := strings.Cut(strings.TrimSpace(params), "="); ok && strings.EqualFold(k, "q") {
+			parsed, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		if q > 0 {
+			entries = append(entries, entry{tag, q})
+		}
+	}
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].q > entries[j].q })
+	out := make([]string, len(entries))
+	for i, e := range entries {
+		out[i] = e.tag
+	}
+	return out
+}
+
+// normalize lower-cases the language and upper-cases a two-letter region,
+// turning "en_us" into "en-US".
+func normalize(tag string) string {
+	tag = strings.ReplaceAll(strings.TrimSpace(tag), "_", "-")
+	parts := strings.Split(tag, "-")
+	for i, p := range parts {
+		if i > 0 && len(p) == 2 {
+			parts[i] = strings.ToUpper(p)
+		} else {
+			parts[i] = strings.ToLower(p)
+		}
+	}
+	return strings.Join(parts, "-")
+}
+
+func format(msg string, kv []any) string {
+	if len(kv) < 2 || !strings.Contains(msg, "{") {
+		return msg
+	}
+	pairs := make([]string, 0, len(kv))
+	for i := 0; i+1 < len(kv); i += 2 {
+		pairs = append(pairs, "{"+fmt.Sprint(kv[i])+"}", fmt.Sprint(kv[i+1]))
+	}
+	return strings.NewReplacer(pairs...).Replace(msg)
+}
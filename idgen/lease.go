@@ -0,0 +1,277 @@
+package idgen
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aatuh/api-toolkit/clock"
+	"github.com/aatuh/api-toolkit/logctx"
+	"github.com/aatuh/api-toolkit/ports"
+)
+
+var (
+	// ErrNoNodeAvailable is returned when every node ID is leased.
+	ErrNoNodeAvailable = errors.New("idgen: no snowflake node id available")
+	// ErrLeaseLost is returned once a node lease could not be renewed in
+	// time or was taken over by another owner.
+	ErrLeaseLost = errors.New("idgen: snowflake node lease lost")
+)
+
+// NodeLeaseOptions configures node ID leasing through Postgres.
+type NodeLeaseOptions struct {
+	Pool ports.DatabasePool
+	// Table holds the leases. Defaults to "idgen_node_leases" and is
+	// created if missing.
+	Table string
+	// NodeBits bounds the node IDs handed out; defaults to 10 to match
+	// SnowflakeOptions.
+	NodeBits uint8
+	// TTL is how long a lease lives without a heartbeat. Defaults to 30s.
+	TTL time.Duration
+	// HeartbeatInterval defaults to TTL/3.
+	HeartbeatInterval time.Duration
+	// Owner identifies this process; defaults to hostname plus a random
+	// suffix.
+	Owner string
+	Clock ports.Clock
+	Log   ports.Logger
+}
+
+// NodeLease is a node ID claimed in the lease table. A background
+// heartbeat extends it until Release is called or the lease is lost.
+// Expiry is judged by the database clock, so replicas need not agree on
+// wall time.
+type NodeLease struct {
+	opts   NodeLeaseOptions
+	table  string
+	nodeID int64
+
+	mu         sync.Mutex
+	validUntil time.Time
+	err        error
+
+	lost   chan struct{}
+	stop   chan struct{}
+	done   chan struct{}
+	closed sync.Once
+}
+
+// AcquireNodeLease claims the lowest free node ID, starting from a random
+// offset to spread concurrent claims, and starts the heartbeat.
+func AcquireNodeLease(ctx context.Context, opts NodeLeaseOptions) (*NodeLease, error) {
+	if opts.Pool == nil {
+		return nil, errors.New("idgen: pool is required")
+	}
+	if opts.Table == "" {
+		opts.Table = "idgen_node_leases"
+	}
+	if opts.NodeBits == 0 {
+		opts.NodeBits = 10
+	}
+	if opts.TTL <= 0 {
+		opts.TTL = 30 * time.Second
+	}
+	if opts.HeartbeatInterval <= 0 || opts.HeartbeatInterval >= opts.TTL {
+		opts.HeartbeatInterval = opts.TTL / 3
+	}
+	if opts.Owner == "" {
+		opts.Owner = defaultOwner()
+	}
+	opts.Clock = clock.OrSystem(opts.Clock)
+	if opts.Log == nil {
+		opts.Log = logctx.FromContext(ctx)
+	}
+
+	l := &NodeLease{
+		opts:  opts,
+		table: quoteIdent(opts.Table),
+		lost:  make(chan struct{}),
+		stop:  make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+	if err := l.ensureTable(ctx); err != nil {
+		return nil, err
+	}
+	start := opts.Clock.Now()
+	id, err := l.claim(ctx)
+	if err != nil {
+		return nil, err
+	}
+	l.nodeID = id
+	l.validUntil = start.Add(opts.TTL)
+	opts.Log.Info("snowflake node lease acquired", "node_id", id, "owner", opts.Owner)
+	go l.heartbeat()
+	return l, nil
+}
+
+// NodeID returns the leased node ID.
+func (l *NodeLease) NodeID() int64 { return l.nodeID }
+
+// Lost is closed once the lease is lost.
+func (l *NodeLease) Lost() <-chan struct{} { return l.lost }
+
+// Err returns ErrLeaseLost once the lease is lost or its local validity
+// window has passed without a successful renewal.
+func (l *NodeLease) Err() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.err != nil {
+		return l.err
+	}
+	if !l.opts.Clock.Now().Before(l.validUntil) {
+		return ErrLeaseLost
+	}
+	return nil
+}
+
+// Release stops the heartbeat and frees the node ID for other replicas.
+func (l *NodeLease) Release(ctx context.Context) error {
+	l.closed.Do(func() { close(l.stop) })
+	<-l.done
+	l.markLost(ErrLeaseLost)
+	_, err := l.exec(ctx, fmt.Sprintf(
+		`DELETE FROM %s WHERE node_id = $1 AND owner = $2;`, l.table),
+		l.nodeID, l.opts.Owner)
+	return err
+}
+
+func (l *NodeLease) heartbeat() {
+	defer close(l.done)
+	t := l.opts.Clock.NewTicker(l.opts.HeartbeatInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-t.C():
+		}
+		start := l.opts.Clock.Now()
+		ctx, cancel := clock.WithTimeout(context.Background(), l.opts.Clock, l.opts.HeartbeatInterval)
+		n, err := l.exec(ctx, fmt.Sprintf(`
+UPDATE %s SET expires_at = now() + $3::bigint * interval '1 millisecond'
+WHERE node_id = $1 AND owner = $2;`, l.table),
+			l.nodeID, l.opts.Owner, l.opts.TTL.Milliseconds())
+		cancel()
+		switch {
+		case err != nil:
+			l.opts.Log.Warn("snowflake node lease renewal failed",
+				"node_id", l.nodeID, "error", err)
+			if l.Err() != nil {
+				l.markLost(ErrLeaseLost)
+				return
+			}
+		case n == 0:
+			l.opts.Log.Error("snowflake node lease taken over", "node_id", l.nodeID)
+			l.markLost(ErrLeaseLost)
+			return
+		default:
+			l.mu.Lock()
+			l.validUntil = start.Add(l.opts.TTL)
+			l.mu.Unlock()
+		}
+	}
+}
+
+func (l *NodeLease) markLost(err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.err == nil {
+		l.err = err
+		close(l.lost)
+	}
+}
+
+func (l *NodeLease) ensureTable(ctx context.Context) error {
+	_, err := l.exec(ctx, fmt.Sprintf(`
+CREATE TABLE IF NOT EXISTS %s (
+  node_id INTEGER PRIMARY KEY,
+  owner TEXT NOT NULL,
+  expires_at TIMESTAMPTZ NOT NULL,
+  acquired_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);`, l.table))
+	return err
+}
+
+// claim inserts or takes over an expired row in one statement. Losing a
+// race yields no row, so it retries a few times with a new offset.
+func (l *NodeLease) claim(ctx context.Context) (int64, error) {
+	maxNode := MaxNodeID(l.opts.NodeBits)
+	q := fmt.Sprintf(`
+INSERT INTO %[1]s AS l (node_id, owner, expires_at)
+SELECT n, $2, now() + $3::bigint * interval '1 millisecond'
+FROM generate_series(0, $1::int) AS n
+WHERE NOT EXISTS (
+  SELECT 1 FROM %[1]s e WHERE e.node_id = n AND e.expires_at > now()
+)
+ORDER BY (n + $4::int) %% ($1::int + 1)
+LIMIT 1
+ON CONFLICT (node_id) DO UPDATE
+SET owner = EXCLUDED.owner, expires_at = EXCLUDED.expires_at, acquired_at = now()
+WHERE l.expires_at <= now()
+RETURNING node_id;`, l.table)
+
+	conn, err := l.opts.Pool.Acquire(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Release()
+	for attempt := 0; attempt < 5; attempt++ {
+		offset, err := rand.Int(rand.Reader, big.NewInt(maxNode+1))
+		if err != nil {
+			return 0, err
+		}
+		rows, err := conn.Query(ctx, q, maxNode, l.opts.Owner,
+			l.opts.TTL.Milliseconds(), offset.Int64())
+		if err != nil {
+			return 0, err
+		}
+		var id int64
+		found := rows.Next()
+		if found {
+			err = rows.Scan(&id)
+		}
+		rows.Close()
+		if err == nil {
+			err = rows.Err()
+		}
+		if err != nil {
+			return 0, err
+		}
+		if found {
+			return id, nil
+		}
+	}
+	return 0, ErrNoNodeAvailable
+}
+
+func (l *NodeLease) exec(ctx context.Context, sql string, args ...any) (int64, error) {
+	conn, err := l.opts.Pool.Acquire(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Release()
+	res, err := conn.Exec(ctx, sql, args...)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected(), nil
+}
+
+func defaultOwner() string {
+	host, _ := os.Hostname()
+	var b [6]byte
+	_, _ = rand.Read(b[:])
+	return host + "-" + hex.EncodeToString(b[:])
+}
+
+func quoteIdent(ident string) string {
+	return `"` + strings.ReplaceAll(ident, `"`, `""`) + `"`
+}
@@ -0,0 +1,124 @@
+package idgen
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aatuh/api-toolkit/ports"
+	"github.com/oklog/ulid/v2"
+)
+
+var (
+	// ErrInvalidID is returned when an ID is neither a ULID nor a UUIDv7.
+	ErrInvalidID = errors.New("idgen: invalid id")
+	// ErrPrefixMismatch is returned when an ID carries an unexpected type
+	// prefix.
+	ErrPrefixMismatch = errors.New("idgen: prefix mismatch")
+)
+
+// PrefixSeparator separates the type prefix from the ID body.
+const PrefixSeparator = "_"
+
+// PrefixedGen produces Stripe-style typed IDs such as "usr_01H...".
+type PrefixedGen struct {
+	prefix string
+	gen    ports.IDGen
+}
+
+// NewPrefixed returns a generator that prefixes IDs from gen. The prefix
+// must be lowercase ASCII letters and digits, starting with a letter. A
+// nil gen uses a monotonic ULID generator.
+func NewPrefixed(prefix string, gen ports.IDGen) ports.IDGen {
+	if !validPrefix(prefix) {
+		panic(fmt.Sprintf("idgen: invalid prefix %q", prefix))
+	}
+	if gen == nil {
+		gen = NewMonotonicULIDGen(nil)
+	}
+	return &PrefixedGen{prefix: prefix, gen: gen}
+}
+
+// New returns the next prefixed ID.
+func (g *PrefixedGen) New() string {
+	return g.prefix + PrefixSeparator + g.gen.New()
+}
+
+// Prefix returns the type prefix without separator.
+func (g *PrefixedGen) Prefix() string { return g.prefix }
+
+// Kind identifies the encoding of an ID body.
+type Kind string
+
+const (
+	KindULID   Kind = "ulid"
+	KindUUIDv7 Kind = "uuidv7"
+)
+
+// ParsedID is the result of Parse.
+type ParsedID struct {
+	Prefix string    // type prefix, empty when absent
+	Body   string    // ID without prefix
+	Kind   Kind      // encoding of Body
+	Time   time.Time // embedded creation time, UTC, millisecond precision
+}
+
+// Parse splits an optional type prefix and extracts the timestamp embedded
+// in a ULID or UUIDv7 body.
+func Parse(id string) (ParsedID, error) {
+	p := ParsedID{Body: id}
+	if prefix, body, ok := strings.Cut(id, PrefixSeparator); ok {
+		if !validPrefix(prefix) {
+			return ParsedID{}, ErrInvalidID
+		}
+		p.Prefix, p.Body = prefix, body
+	}
+	switch len(p.Body) {
+	case ulid.EncodedSize:
+		u, err := ulid.ParseStrict(p.Body)
+		if err != nil {
+			return ParsedID{}, fmt.Errorf("%w: %v", ErrInvalidID, err)
+		}
+		p.Kind = KindULID
+		p.Time = ulid.Time(u.Time()).UTC()
+	case 36:
+		b, ok := parseUUID(p.Body)
+		if !ok || b[6]>>4 != 7 || b[8]&0xC0 != 0x80 {
+			return ParsedID{}, ErrInvalidID
+		}
+		ms := int64(b[0])<<40 | int64(b[1])<<32 | int64(b[2])<<24 |
+			int64(b[3])<<16 | int64(b[4])<<8 | int64(b[5])
+		p.Kind = KindUUIDv7
+		p.Time = time.UnixMilli(ms).UTC()
+	default:
+		return ParsedID{}, ErrInvalidID
+	}
+	return p, nil
+}
+
+// ParseWithPrefix is Parse that also requires the given type prefix.
+func ParseWithPrefix(id, prefix string) (ParsedID, error) {
+	p, err := Parse(id)
+	if err != nil {
+		return ParsedID{}, err
+	}
+	if p.Prefix != prefix {
+		return ParsedID{}, fmt.Errorf("%w: want %q, got %q",
+			ErrPrefixMismatch, prefix, p.Prefix)
+	}
+	return p, nil
+}
+
+func validPrefix(s string) bool {
+	if s == "" || s[0] < 'a' || s[0] > 'z' {
+		return false
+	}
+	for i := 1; i < len(s); i++ {
+		c := s[i]
+		if !((c >= 'a' && c <= 'z') || (c >= '0' && c <= '9')) {
+			return false
+		}
+	}
+	return true
+}
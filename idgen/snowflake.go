@@ -0,0 +1,201 @@
+package idgen
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/aatuh/api-toolkit/clock"
+	"github.com/aatuh/api-toolkit/ports"
+)
+
+var (
+	// ErrClockRollback is returned when the clock moved backwards further
+	// than the rollback policy tolerates.
+	ErrClockRollback = errors.New("idgen: clock moved backwards")
+	// ErrTimestampOverflow is returned once the timestamp no longer fits
+	// in the bits left after the node and sequence fields.
+	ErrTimestampOverflow = errors.New("idgen: timestamp overflow")
+)
+
+// RollbackPolicy decides what a Snowflake does when the clock goes back.
+type RollbackPolicy int
+
+const (
+	// RollbackWait blocks until the clock catches up, for at most
+	// SnowflakeOptions.MaxRollbackWait, then fails with ErrClockRollback.
+	RollbackWait RollbackPolicy = iota
+	// RollbackError fails immediately with ErrClockRollback.
+	RollbackError
+	// RollbackContinue keeps issuing IDs from the last timestamp seen,
+	// borrowing future milliseconds when the sequence runs out.
+	RollbackContinue
+)
+
+// DefaultSnowflakeEpoch is the default custom epoch (2020-01-01 UTC).
+var DefaultSnowflakeEpoch = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// SnowflakeOptions configures a Snowflake generator. The layout is
+// sign(1) | timestamp | node(NodeBits) | sequence(SequenceBits).
+type SnowflakeOptions struct {
+	Epoch        time.Time // defaults to DefaultSnowflakeEpoch
+	NodeBits     uint8     // defaults to 10 (1024 nodes)
+	SequenceBits uint8     // defaults to 12 (4096 IDs per ms per node)
+	// NodeID identifies this generator. Ignored when Lease is set.
+	NodeID int64
+	// Lease supplies the node ID and stops generation once it is lost.
+	Lease           *NodeLease
+	Clock           ports.Clock
+	Rollback        RollbackPolicy
+	MaxRollbackWait time.Duration // defaults to 1s
+}
+
+// Snowflake generates compact, roughly time-ordered int64 IDs. It is safe
+// for concurrent use.
+type Snowflake struct {
+	opts     SnowflakeOptions
+	clock    ports.Clock
+	epochMS  int64
+	maxTS    int64
+	maxSeq   int64
+	nodeID   int64
+	nodeSh   uint8
+	tsShift  uint8
+	mu       sync.Mutex
+	lastTS   int64
+	sequence int64
+}
+
+// NewSnowflake validates opts and returns a generator.
+func NewSnowflake(opts SnowflakeOptions) (*Snowflake, error) {
+	if opts.Epoch.IsZero() {
+		opts.Epoch = DefaultSnowflakeEpoch
+	}
+	if opts.NodeBits == 0 {
+		opts.NodeBits = 10
+	}
+	if opts.SequenceBits == 0 {
+		opts.SequenceBits = 12
+	}
+	if opts.MaxRollbackWait <= 0 {
+		opts.MaxRollbackWait = time.Second
+	}
+	if int(opts.NodeBits)+int(opts.SequenceBits) > 31 {
+		return nil, fmt.Errorf("idgen: node and sequence bits exceed 31 (%d+%d)",
+			opts.NodeBits, opts.SequenceBits)
+	}
+	node := opts.NodeID
+	if opts.Lease != nil {
+		node = opts.Lease.NodeID()
+	}
+	maxNode := int64(1)<<opts.NodeBits - 1
+	if node < 0 || node > maxNode {
+		return nil, fmt.Errorf("idgen: node id %d out of range [0, %d]", node, maxNode)
+	}
+	tsBits := 63 - opts.NodeBits - opts.SequenceBits
+	return &Snowflake{
+		opts:    opts,
+		clock:   clock.OrSystem(opts.Clock),
+		epochMS: opts.Epoch.UnixMilli(),
+		maxTS:   int64(1)<<tsBits - 1,
+		maxSeq:  int64(1)<<opts.SequenceBits - 1,
+		nodeID:  node,
+		nodeSh:  opts.SequenceBits,
+		tsShift: opts.NodeBits + opts.SequenceBits,
+		lastTS:  -1,
+	}, nil
+}
+
+// MaxNodeID returns the largest node ID representable with nodeBits.
+func MaxNodeID(nodeBits uint8) int64 { return int64(1)<<nodeBits - 1 }
+
+// NodeID returns the node ID embedded in generated IDs.
+func (s *Snowflake) NodeID() int64 { return s.nodeID }
+
+// New returns the next ID in decimal form, implementing ports.IDGen. It
+// panics where NextID would return an error.
+func (s *Snowflake) New() string {
+	id, err := s.NextID()
+	if err != nil {
+		panic(err)
+	}
+	return strconv.FormatInt(id, 10)
+}
+
+// NextID returns the next ID.
+func (s *Snowflake) NextID() (int64, error) {
+	if l := s.opts.Lease; l != nil {
+		if err := l.Err(); err != nil {
+			return 0, err
+		}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ts := s.now()
+	if ts < s.lastTS {
+		var err error
+		if ts, err = s.handleRollback(ts); err != nil {
+			return 0, err
+		}
+	}
+	if ts == s.lastTS {
+		s.sequence++
+		if s.sequence > s.maxSeq {
+			if s.opts.Rollback == RollbackContinue && s.now() <= s.lastTS {
+				ts = s.lastTS + 1
+			} else {
+				ts = s.waitAfter(s.lastTS)
+			}
+			s.sequence = 0
+		}
+	} else {
+		s.sequence = 0
+	}
+	if ts > s.maxTS {
+		return 0, ErrTimestampOverflow
+	}
+	s.lastTS = ts
+	return ts<<s.tsShift | s.nodeID<<s.nodeSh | s.sequence, nil
+}
+
+// Decompose splits an ID into its creation time, node and sequence.
+func (s *Snowflake) Decompose(id int64) (t time.Time, node, seq int64) {
+	ts := id >> s.tsShift
+	node = (id >> s.nodeSh) & (int64(1)<<s.opts.NodeBits - 1)
+	seq = id & s.maxSeq
+	return time.UnixMilli(s.epochMS + ts).UTC(), node, seq
+}
+
+func (s *Snowflake) now() int64 {
+	return s.clock.Now().UnixMilli() - s.epochMS
+}
+
+func (s *Snowflake) handleRollback(ts int64) (int64, error) {
+	drift := time.Duration(s.lastTS-ts) * time.Millisecond
+	switch s.opts.Rollback {
+	case RollbackContinue:
+		return s.lastTS, nil
+	case RollbackWait:
+		if drift <= s.opts.MaxRollbackWait {
+			<-s.clock.After(drift)
+			if ts = s.now(); ts >= s.lastTS {
+				return ts, nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("%w by %s", ErrClockRollback, drift)
+}
+
+// waitAfter blocks until the clock passes ts.
+func (s *Snowflake) waitAfter(ts int64) int64 {
+	for {
+		now := s.now()
+		if now > ts {
+			return now
+		}
+		<-s.clock.After(time.Duration(ts-now+1) * time.Millisecond)
+	}
+}
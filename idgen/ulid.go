@@ -2,12 +2,17 @@ package idgen
 
 import (
 	"crypto/rand"
+	"errors"
+	"sync"
 	"time"
 
+	"github.com/aatuh/api-toolkit/clock"
 	"github.com/aatuh/api-toolkit/ports"
 	"github.com/oklog/ulid/v2"
 )
 
+// ULIDGen generates independent ULIDs from the wall clock. IDs created in
+// the same millisecond do not sort; prefer MonotonicULIDGen.
 type ULIDGen struct{}
 
 func (ULIDGen) New() string {
@@ -16,6 +21,59 @@ func (ULIDGen) New() string {
 }
 
 // NewULIDGen creates a new ULID generator that implements ports.IDGen.
+// IDs are monotonic and use the system clock.
 func NewULIDGen() ports.IDGen {
-	return &ULIDGen{}
+	return NewMonotonicULIDGen(nil)
+}
+
+// MonotonicULIDGen generates ULIDs that sort in creation order, including
+// within the same millisecond and across small wall clock rollbacks. It is
+// safe for concurrent use.
+type MonotonicULIDGen struct {
+	mu      sync.Mutex
+	clock   ports.Clock
+	entropy *ulid.MonotonicEntropy
+	lastMS  uint64
+}
+
+// NewMonotonicULIDGen creates a monotonic generator timed by clk. A nil clk
+// uses the system clock.
+func NewMonotonicULIDGen(clk ports.Clock) ports.IDGen {
+	return &MonotonicULIDGen{
+		clock:   clock.OrSystem(clk),
+		entropy: ulid.Monotonic(rand.Reader, 0),
+	}
+}
+
+// New returns the next ULID as a string. It panics if the system random
+// source fails, mirroring ulid.MustNew.
+func (g *MonotonicULIDGen) New() string {
+	id, err := g.NewULID()
+	if err != nil {
+		panic(err)
+	}
+	return id.String()
+}
+
+// NewULID returns the next ULID.
+func (g *MonotonicULIDGen) NewULID() (ulid.ULID, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	ms := ulid.Timestamp(g.clock.Now())
+	// Never go backwards: reuse the last millisecond and let the entropy
+	// increment keep ordering.
+	if ms < g.lastMS {
+		ms = g.lastMS
+	}
+	id, err := ulid.New(ms, g.entropy)
+	if errors.Is(err, ulid.ErrMonotonicOverflow) {
+		// Entropy exhausted within this millisecond; borrow the next one.
+		ms++
+		id, err = ulid.New(ms, g.entropy)
+	}
+	if err != nil {
+		return ulid.ULID{}, err
+	}
+	g.lastMS = ms
+	return id, nil
 }
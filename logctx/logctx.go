@@ -0,0 +1,57 @@
+package logctx
+
+import (
+	"context"
+
+	"github.com/aatuh/api-toolkit/ports"
+)
+
+type ctxKey struct{}
+
+// IntoContext returns a copy of ctx that carries the given logger.
+func IntoContext(ctx context.Context, log ports.Logger) context.Context {
+	if log == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, ctxKey{}, log)
+}
+
+// FromContext returns the logger stored in ctx. When none is present a
+// no-op logger is returned, so callers never need a nil check.
+func FromContext(ctx context.Context) ports.Logger {
+	if l, ok := Lookup(ctx); ok {
+		return l
+	}
+	return Nop()
+}
+
+// Lookup returns the logger stored in ctx and whether one was present.
+func Lookup(ctx context.Context) (ports.Logger, bool) {
+	if ctx == nil {
+		return nil, false
+	}
+	l, ok := ctx.Value(ctxKey{}).(ports.Logger)
+	return l, ok && l != nil
+}
+
+// With derives a child of the context logger with kv attached and stores
+// it back into the returned context. It is a no-op when ctx carries no
+// logger.
+func With(ctx context.Context, kv ...any) context.Context {
+	l, ok := Lookup(ctx)
+	if !ok || len(kv) == 0 {
+		return ctx
+	}
+	return IntoContext(ctx, l.With(kv...))
+}
+
+// Nop returns a logger that discards everything.
+func Nop() ports.Logger { return nopLogger{} }
+
+type nopLogger struct{}
+
+func (nopLogger) Debug(string, ...any)       {}
+func (nopLogger) Info(string, ...any)        {}
+func (nopLogger) Warn(string, ...any)        {}
+func (nopLogger) Error(string, ...any)       {}
+func (n nopLogger) With(...any) ports.Logger { return n }
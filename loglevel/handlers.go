@@ -0,0 +1,107 @@
+package loglevel
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/aatuh/api-toolkit/httpx"
+	"github.com/aatuh/api-toolkit/ports"
+	"github.com/aatuh/api-toolkit/response_writer"
+	"github.com/aatuh/api-toolkit/specs"
+)
+
+// State is the payload read and written by the log level endpoint.
+// In requests, a package mapped to "" removes its override.
+type State struct {
+	Level    string            `json:"level,omitempty" example:"info"`
+	Packages map[string]string `json:"packages,omitempty"`
+}
+
+// Handler exposes a ports.LogLevelController over HTTP. Mount it on an
+// internal or authenticated router only.
+type Handler struct {
+	ctrl ports.LogLevelController
+}
+
+// NewHandler creates a new log level handler.
+func NewHandler(ctrl ports.LogLevelController) *Handler {
+	return &Handler{ctrl: ctrl}
+}
+
+// GetHandler returns the current levels.
+// @Summary Get log level
+// @Description Returns the global log level and per-package overrides
+// @Tags system
+// @Produce json
+// @Success 200 {object} State "Current log levels"
+// @Router /loglevel [get]
+func (h *Handler) GetHandler(w http.ResponseWriter, r *http.Request) {
+	response_writer.WriteJSON(w, http.StatusOK, h.state())
+}
+
+// SetHandler changes the global level and/or package overrides.
+// @Summary Set log level
+// @Description Changes the global log level and per-package overrides
+// @Tags system
+// @Accept json
+// @Produce json
+// @Param body body State true "Levels to apply"
+// @Success 200 {object} State "Updated log levels"
+// @Failure 400 {object} map[string]interface{} "Invalid level"
+// @Router /loglevel [put]
+func (h *Handler) SetHandler(w http.ResponseWriter, r *http.Request) {
+	var in State
+	if err := httpx.DecodeJSON(r, &in); err != nil {
+		httpx.WriteError(w, r, err)
+		return
+	}
+	// Validate everything first so a rejected request changes nothing.
+	if in.Level != "" {
+		if err := h.ctrl.ValidateLevel(in.Level); err != nil {
+			httpx.WriteSimpleProblem(w, http.StatusBadRequest,
+				"Invalid log level", err.Error())
+			return
+		}
+	}
+	for pkg, level := range in.Packages {
+		if strings.TrimSuffix(strings.TrimSpace(pkg), "/") == "" {
+			httpx.WriteSimpleProblem(w, http.StatusBadRequest,
+				"Invalid log level", "package is required")
+			return
+		}
+		if level == "" {
+			continue
+		}
+		if err := h.ctrl.ValidateLevel(level); err != nil {
+			httpx.WriteSimpleProblem(w, http.StatusBadRequest,
+				"Invalid log level", pkg+": "+err.Error())
+			return
+		}
+	}
+	if in.Level != "" {
+		if err := h.ctrl.SetLevel(in.Level); err != nil {
+			httpx.WriteError(w, r, err)
+			return
+		}
+	}
+	for pkg, level := range in.Packages {
+		if err := h.ctrl.SetPackageLevel(pkg, level); err != nil {
+			httpx.WriteError(w, r, err)
+			return
+		}
+	}
+	response_writer.WriteJSON(w, http.StatusOK, h.state())
+}
+
+// RegisterRoutes registers the log level endpoint on the given router.
+func (h *Handler) RegisterRoutes(router interface {
+	Get(pattern string, h http.HandlerFunc)
+	Put(pattern string, h http.HandlerFunc)
+}) {
+	router.Get(specs.LogLevel, h.GetHandler)
+	router.Put(specs.LogLevel, h.SetHandler)
+}
+
+func (h *Handler) state() State {
+	return State{Level: h.ctrl.Level(), Packages: h.ctrl.PackageLevels()}
+}
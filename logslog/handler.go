@@ -0,0 +1,123 @@
+package logslog
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/aatuh/api-toolkit/ports"
+)
+
+// HandlerOptions configures the slog bridge.
+type HandlerOptions struct {
+	// Level is the minimum level handled. Defaults to slog.LevelDebug so
+	// that filtering is left to the wrapped ports.Logger.
+	Level slog.Leveler
+}
+
+// Handler exposes a ports.Logger as an slog.Handler, so libraries that log
+// through slog end up in the same sink. Groups are flattened into dotted
+// keys ("req.method") since ports.Logger has no notion of nesting.
+type Handler struct {
+	log    ports.Logger
+	level  slog.Leveler
+	prefix string
+}
+
+// NewHandler returns an slog.Handler writing to log. When log is itself
+// backed by slog and opts is nil, its handler is returned unchanged.
+func NewHandler(log ports.Logger, opts *HandlerOptions) slog.Handler {
+	if l, ok := log.(*Logger); ok && opts == nil {
+		return l.h
+	}
+	h := &Handler{log: log, level: slog.LevelDebug}
+	if opts != nil && opts.Level != nil {
+		h.level = opts.Level
+	}
+	return h
+}
+
+// NewSlog is a convenience returning an *slog.Logger backed by log.
+func NewSlog(log ports.Logger) *slog.Logger {
+	return slog.New(NewHandler(log, nil))
+}
+
+// Enabled reports whether level is at or above the configured minimum.
+func (h *Handler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level.Level()
+}
+
+// Handle forwards the record to the ports.Logger method matching its
+// level. Levels between the four standard ones are rounded down and the
+// original level is preserved under "slog_level".
+func (h *Handler) Handle(_ context.Context, rec slog.Record) error {
+	kv := make([]any, 0, rec.NumAttrs()*2+2)
+	rec.Attrs(func(a slog.Attr) bool {
+		kv = appendAttr(kv, h.prefix, a)
+		return true
+	})
+	switch rec.Level {
+	case slog.LevelDebug, slog.LevelInfo, slog.LevelWarn, slog.LevelError:
+	default:
+		kv = append(kv, "slog_level", rec.Level.String())
+	}
+	switch {
+	case rec.Level < slog.LevelInfo:
+		h.log.Debug(rec.Message, kv...)
+	case rec.Level < slog.LevelWarn:
+		h.log.Info(rec.Message, kv...)
+	case rec.Level < slog.LevelError:
+		h.log.Warn(rec.Message, kv...)
+	default:
+		h.log.Error(rec.Message, kv...)
+	}
+	return nil
+}
+
+// WithAttrs returns a handler whose logger carries attrs on every entry.
+func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	var kv []any
+	for _, a := range attrs {
+		kv = appendAttr(kv, h.prefix, a)
+	}
+	if len(kv) == 0 {
+		return h
+	}
+	c := *h
+	c.log = h.log.With(kv...)
+	return &c
+}
+
+// WithGroup returns a handler that prefixes subsequent keys with name.
+func (h *Handler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	c := *h
+	c.prefix = h.prefix + name + "."
+	return &c
+}
+
+// appendAttr flattens a into key/value pairs following the slog handler
+// rules: values are resolved, empty attrs are dropped and groups with an
+// empty key are inlined.
+func appendAttr(kv []any, prefix string, a slog.Attr) []any {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return kv
+	}
+	if a.Value.Kind() == slog.KindGroup {
+		group := a.Value.Group()
+		if len(group) == 0 {
+			return kv
+		}
+		p := prefix
+		if a.Key != "" {
+			p = prefix + a.Key + "."
+		}
+		for _, ga := range group {
+			kv = appendAttr(kv, p, ga)
+		}
+		return kv
+	}
+	return append(kv, prefix+a.Key, a.Value.Any())
+}
@@ -0,0 +1,94 @@
+package logslog
+
+import (
+	"context"
+	"log/slog"
+	"runtime"
+	"time"
+
+	"github.com/aatuh/api-toolkit/ports"
+)
+
+// Logger adapts an slog.Handler to the ports.Logger interface.
+type Logger struct {
+	h    slog.Handler
+	skip int
+}
+
+// New returns a ports.Logger that writes through h.
+func New(h slog.Handler) ports.Logger {
+	if h == nil {
+		h = slog.Default().Handler()
+	}
+	return &Logger{h: h}
+}
+
+// FromSlog returns a ports.Logger that writes through l's handler.
+func FromSlog(l *slog.Logger) ports.Logger {
+	if l == nil {
+		l = slog.Default()
+	}
+	return New(l.Handler())
+}
+
+func (l *Logger) Debug(msg string, kv ...any) { l.log(slog.LevelDebug, msg, kv) }
+func (l *Logger) Info(msg string, kv ...any)  { l.log(slog.LevelInfo, msg, kv) }
+func (l *Logger) Warn(msg string, kv ...any)  { l.log(slog.LevelWarn, msg, kv) }
+func (l *Logger) Error(msg string, kv ...any) { l.log(slog.LevelError, msg, kv) }
+
+// With returns a child logger that adds kv to every entry. Pairs follow
+// slog conventions, so slog.Attr and slog.Group values are accepted.
+func (l *Logger) With(kv ...any) ports.Logger {
+	attrs := toAttrs(kv)
+	if len(attrs) == 0 {
+		return l
+	}
+	return &Logger{h: l.h.WithAttrs(attrs), skip: l.skip}
+}
+
+// WithGroup returns a child logger that nests subsequent attributes under
+// name, mirroring slog.Logger.WithGroup.
+func (l *Logger) WithGroup(name string) ports.Logger {
+	if name == "" {
+		return l
+	}
+	return &Logger{h: l.h.WithGroup(name), skip: l.skip}
+}
+
+// WithCallerSkip implements ports.CallerSkipper.
+func (l *Logger) WithCallerSkip(skip int) ports.Logger {
+	return &Logger{h: l.h, skip: l.skip + skip}
+}
+
+// Handler returns the underlying slog.Handler.
+func (l *Logger) Handler() slog.Handler { return l.h }
+
+func (l *Logger) log(level slog.Level, msg string, kv []any) {
+	ctx := context.Background()
+	if !l.h.Enabled(ctx, level) {
+		return
+	}
+	// Skip runtime.Callers, log and the exported level method so the
+	// source location points at the caller, plus any decorator frames.
+	var pcs [1]uintptr
+	runtime.Callers(3+l.skip, pcs[:])
+	rec := slog.NewRecord(time.Now(), level, msg, pcs[0])
+	rec.Add(kv...)
+	_ = l.h.Handle(ctx, rec)
+}
+
+// toAttrs converts loose key/value pairs using slog's own rules, so odd
+// arguments end up under !BADKEY exactly as with slog.Logger.
+func toAttrs(kv []any) []slog.Attr {
+	if len(kv) == 0 {
+		return nil
+	}
+	var rec slog.Record
+	rec.Add(kv...)
+	attrs := make([]slog.Attr, 0, rec.NumAttrs())
+	rec.Attrs(func(a slog.Attr) bool {
+		attrs = append(attrs, a)
+		return true
+	})
+	return attrs
+}
@@ -0,0 +1,179 @@
+package logzap
+
+import (
+	"errors"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// levelState holds the global level and optional per-package overrides shared
+// by a logger and all of its children. Overrides are keyed by Go import
+// path and also apply to sub-packages; the longest match wins.
+type levelState struct {
+	base  zap.AtomicLevel
+	floor zap.AtomicLevel
+
+	mu      sync.RWMutex
+	pkgs    map[string]zapcore.Level
+	hasPkgs atomic.Bool
+	callers sync.Map // pc -> package path
+}
+
+func newLevelState(l zapcore.Level) *levelState {
+	return &levelState{
+		base:  zap.NewAtomicLevelAt(l),
+		floor: zap.NewAtomicLevelAt(l),
+		pkgs:  make(map[string]zapcore.Level),
+	}
+}
+
+// Level returns the global level name.
+func (lv *levelState) Level() string { return lv.base.Level().String() }
+
+// SetLevel changes the global level.
+func (lv *levelState) SetLevel(level string) error {
+	l, err := zapcore.ParseLevel(level)
+	if err != nil {
+		return err
+	}
+	lv.mu.Lock()
+	defer lv.mu.Unlock()
+	lv.base.SetLevel(l)
+	lv.updateFloor()
+	return nil
+}
+
+// ValidateLevel parses level without applying it.
+func (lv *levelState) ValidateLevel(level string) error {
+	_, err := zapcore.ParseLevel(level)
+	return err
+}
+
+// PackageLevels returns a copy of the per-package overrides.
+func (lv *levelState) PackageLevels() map[string]string {
+	lv.mu.RLock()
+	defer lv.mu.RUnlock()
+	out := make(map[string]string, len(lv.pkgs))
+	for p, l := range lv.pkgs {
+		out[p] = l.String()
+	}
+	return out
+}
+
+// SetPackageLevel overrides the level for pkg; an empty level removes the
+// override.
+func (lv *levelState) SetPackageLevel(pkg, level string) error {
+	pkg = strings.TrimSuffix(strings.TrimSpace(pkg), "/")
+	if pkg == "" {
+		return errors.New("package is required")
+	}
+	lv.mu.Lock()
+	defer lv.mu.Unlock()
+	if level == "" {
+		delete(lv.pkgs, pkg)
+	} else {
+		l, err := zapcore.ParseLevel(level)
+		if err != nil {
+			return err
+		}
+		lv.pkgs[pkg] = l
+	}
+	lv.hasPkgs.Store(len(lv.pkgs) > 0)
+	lv.updateFloor()
+	return nil
+}
+
+// updateFloor lowers the core level to the most verbose level in use.
+// Callers must hold lv.mu.
+func (lv *levelState) updateFloor() {
+	floor := lv.base.Level()
+	for _, l := range lv.pkgs {
+		if l < floor {
+			floor = l
+		}
+	}
+	lv.floor.SetLevel(floor)
+}
+
+// enabled reports whether level should be logged for the calling package.
+// The caller lookup only happens while overrides exist.
+func (lv *levelState) enabled(level zapcore.Level) bool {
+	if !lv.hasPkgs.Load() {
+		return lv.base.Enabled(level)
+	}
+	pkg := lv.callerPackage()
+	lv.mu.RLock()
+	defer lv.mu.RUnlock()
+	best := -1
+	var bestLevel zapcore.Level
+	for p, l := range lv.pkgs {
+		if len(p) > best && (pkg == p || strings.HasPrefix(pkg, p+"/")) {
+			best, bestLevel = len(p), l
+		}
+	}
+	if best >= 0 {
+		return level >= bestLevel
+	}
+	return lv.base.Enabled(level)
+}
+
+// callerPackage returns the import path of the first caller outside the
+// toolkit's logging packages.
+func (lv *levelState) callerPackage() string {
+	var pcs [16]uintptr
+	n := runtime.Callers(3, pcs[:])
+	for _, pc := range pcs[:n] {
+		pkg := lv.packageOf(pc)
+		if !isLoggingPackage(pkg) {
+			return pkg
+		}
+	}
+	return ""
+}
+
+func (lv *levelState) packageOf(pc uintptr) string {
+	if v, ok := lv.callers.Load(pc); ok {
+		return v.(string)
+	}
+	pkg := ""
+	if fn := runtime.FuncForPC(pc - 1); fn != nil {
+		pkg = packageName(fn.Name())
+	}
+	lv.callers.Store(pc, pkg)
+	return pkg
+}
+
+// packageName extracts the import path from a fully qualified function
+// name such as "example.com/svc/billing.(*Service).Charge".
+func packageName(fn string) string {
+	slash := strings.LastIndexByte(fn, '/')
+	if dot := strings.IndexByte(fn[slash+1:], '.'); dot >= 0 {
+		return fn[:slash+1+dot]
+	}
+	return fn
+}
+
+const modulePath = "github.com/aatuh/api-toolkit/"
+
+// loggingPackages are skipped when resolving the caller so that wrappers
+// and decorators do not hide the package that actually logged.
+var loggingPackages = []string{
+	modulePath + "logzap",
+	modulePath + "logslog",
+	modulePath + "logctx",
+	modulePath + "redact",
+}
+
+func isLoggingPackage(pkg string) bool {
+	for _, p := range loggingPackages {
+		if pkg == p {
+			return true
+		}
+	}
+	return false
+}
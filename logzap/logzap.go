@@ -1,22 +1,98 @@
 package logzap
 
 import (
+	"github.com/aatuh/api-toolkit/envvar"
 	"github.com/aatuh/api-toolkit/ports"
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 )
 
-// ZapLogger adapts zap to the shared.Logger interface.
-type ZapLogger struct{ s *zap.SugaredLogger }
+// ZapLogger adapts zap to the shared.Logger interface. It also implements
+// ports.LogLevelController so verbosity can be changed at runtime.
+type ZapLogger struct {
+	s      *zap.SugaredLogger
+	levels *levelState
+}
 
-func New(z *zap.Logger) ports.Logger { return &ZapLogger{s: z.Sugar()} }
+// New wraps an existing zap logger. Its core decides the lowest level that
+// can ever be emitted; runtime level changes filter on top of that.
+func New(z *zap.Logger) ports.Logger {
+	return &ZapLogger{s: z.Sugar(), levels: newLevelState(z.Level())}
+}
 
-// NewProduction creates a production logger.
+// NewProduction creates a production logger honoring the LOG_LEVEL
+// environment variable (default "info").
 func NewProduction() ports.Logger {
-	z, _ := zap.NewProduction()
-	return &ZapLogger{s: z.Sugar()}
+	return NewProductionWithLevel(envvar.New().GetOr("LOG_LEVEL", "info"))
+}
+
+// NewProductionWithLevel creates a production logger starting at level,
+// typically config.Config.LogLevel. Unknown levels fall back to info.
+func NewProductionWithLevel(level string) ports.Logger {
+	lvl, err := zapcore.ParseLevel(level)
+	if err != nil {
+		lvl = zapcore.InfoLevel
+	}
+	levels := newLevelState(lvl)
+	cfg := zap.NewProductionConfig()
+	// The core admits everything down to the most verbose level in use;
+	// ZapLogger narrows it per call site.
+	cfg.Level = levels.floor
+	z, _ := cfg.Build(zap.AddCallerSkip(1))
+	return &ZapLogger{s: z.Sugar(), levels: levels}
+}
+
+func (l *ZapLogger) Debug(msg string, kv ...any) {
+	if l.levels.enabled(zapcore.DebugLevel) {
+		l.s.Debugw(msg, kv...)
+	}
 }
 
-func (l *ZapLogger) Debug(msg string, kv ...any) { l.s.Debugw(msg, kv...) }
-func (l *ZapLogger) Info(msg string, kv ...any)  { l.s.Infow(msg, kv...) }
-func (l *ZapLogger) Warn(msg string, kv ...any)  { l.s.Warnw(msg, kv...) }
-func (l *ZapLogger) Error(msg string, kv ...any) { l.s.Errorw(msg, kv...) }
+func (l *ZapLogger) Info(msg string, kv ...any) {
+	if l.levels.enabled(zapcore.InfoLevel) {
+		l.s.Infow(msg, kv...)
+	}
+}
+
+func (l *ZapLogger) Warn(msg string, kv ...any) {
+	if l.levels.enabled(zapcore.WarnLevel) {
+		l.s.Warnw(msg, kv...)
+	}
+}
+
+func (l *ZapLogger) Error(msg string, kv ...any) {
+	if l.levels.enabled(zapcore.ErrorLevel) {
+		l.s.Errorw(msg, kv...)
+	}
+}
+
+// With returns a child logger that adds kv to every entry. Children share
+// the parent's level settings.
+func (l *ZapLogger) With(kv ...any) ports.Logger {
+	if len(kv) == 0 {
+		return l
+	}
+	return &ZapLogger{s: l.s.With(kv...), levels: l.levels}
+}
+
+// WithCallerSkip implements ports.CallerSkipper.
+func (l *ZapLogger) WithCallerSkip(skip int) ports.Logger {
+	return &ZapLogger{s: l.s.WithOptions(zap.AddCallerSkip(skip)), levels: l.levels}
+}
+
+// Level returns the global level name.
+func (l *ZapLogger) Level() string { return l.levels.Level() }
+
+// SetLevel changes the global level.
+func (l *ZapLogger) SetLevel(level string) error { return l.levels.SetLevel(level) }
+
+// ValidateLevel reports whether level is a valid level name.
+func (l *ZapLogger) ValidateLevel(level string) error { return l.levels.ValidateLevel(level) }
+
+// PackageLevels returns the active per-package overrides.
+func (l *ZapLogger) PackageLevels() map[string]string { return l.levels.PackageLevels() }
+
+// SetPackageLevel overrides the level for pkg; an empty level removes it.
+func (l *ZapLogger) SetPackageLevel(pkg, level string) error {
+	return l.levels.SetPackageLevel(pkg, level)
+}
@@ -0,0 +1,407 @@
+package compress
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/aatuh/api-toolkit/httpx"
+)
+
+// DefaultSkipTypes are media types that are already compressed. Entries
+// ending in "/" match a whole top-level type.
+var DefaultSkipTypes = []string{
+	"image/",
+	"video/",
+	"audio/",
+	"font/woff",
+	"font/woff2",
+	"application/zip",
+	"application/gzip",
+	"application/x-gzip",
+	"application/zstd",
+	"application/x-bzip2",
+	"application/x-xz",
+	"application/x-7z-compressed",
+	"application/x-rar-compressed",
+	"application/pdf",
+	"application/octet-stream",
+}
+
+// Options configures the compression middleware.
+type Options struct {
+	// MinSize is the smallest response body, in bytes, worth compressing.
+	// Smaller responses are sent as they are. Defaults to 1024.
+	MinSize int
+	// Encoders are the response encodings offered, in server preference
+	// order for equally weighted Accept-Encoding entries. Defaults to
+	// Gzip and Deflate.
+	Encoders []Encoder
+	// SkipTypes are media types sent uncompressed. Structured +json and
+	// +xml types are always compressed. Defaults to DefaultSkipTypes.
+	SkipTypes []string
+
+	// DecompressRequests decodes request bodies sent with a
+	// Content-Encoding listed in Decoders; other encodings get 415.
+	DecompressRequests bool
+	// Decoders are the accepted request encodings. Defaults to Gzip and
+	// Deflate.
+	Decoders []Decoder
+	// MaxDecompressedBytes caps a decoded request body. Reading past it
+	// fails with *http.MaxBytesError, as with maxbody, which httpx maps
+	// to 413. Defaults to 10 MiB.
+	MaxDecompressedBytes int64
+}
+
+type Middleware struct {
+	opts Options
+}
+
+// New returns the compression middleware. It picks a response encoding
+// from Accept-Encoding, buffers up to MinSize bytes to decide whether
+// compressing is worthwhile, and always adds Vary: Accept-Encoding.
+// Flushes pass through the encoder, so streaming responses keep working.
+// HEAD gets the headers GET would, without a body. A strong ETag on an
+// encoded response gets the coding as a suffix ("abc" becomes
+// "abc-gzip"), since the encoded bytes are a different representation;
+// middleware/conditional ignores the suffix when comparing, so If-Match
+// round-trips still match.
+func New(opts Options) *Middleware {
+	if opts.MinSize <= 0 {
+		opts.MinSize = 1024
+	}
+	if opts.Encoders == nil {
+		opts.Encoders = []Encoder{Gzip{}, Deflate{}}
+	}
+	if opts.SkipTypes == nil {
+		opts.SkipTypes = DefaultSkipTypes
+	}
+	if opts.Decoders == nil {
+		opts.Decoders = []Decoder{Gzip{}, Deflate{}}
+	}
+	if opts.MaxDecompressedBytes <= 0 {
+		opts.MaxDecompressedBytes = 10 << 20
+	}
+	return &Middleware{opts: opts}
+}
+
+func (m *Middleware) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if m.opts.DecompressRequests && !m.decodeBody(w, r) {
+			return
+		}
+		addVary(w.Header(), "Accept-Encoding")
+		enc := m.negotiate(strings.Join(r.Header.Values("Accept-Encoding"), ","))
+		if enc == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		cw := &compressWriter{
+			ResponseWriter: w, m: m, enc: enc,
+			head:        r.Method == http.MethodHead,
+			ifNoneMatch: r.Header.Get("If-None-Match"),
+		}
+		next.ServeHTTP(cw, r)
+		cw.close()
+	})
+}
+
+// decodeBody swaps r.Body for a capped decoding reader. It reports false
+// after writing an error response.
+func (m *Middleware) decodeBody(w http.ResponseWriter, r *http.Request) bool {
+	ce := strings.TrimSpace(r.Header.Get("Content-Encoding"))
+	if ce == "" || strings.EqualFold(ce, "identity") || r.Body == nil || r.Body == http.NoBody {
+		return true
+	}
+	var dec Decoder
+	for _, d := range m.opts.Decoders {
+		if strings.EqualFold(d.Encoding(), ce) {
+			dec = d
+			break
+		}
+	}
+	if dec == nil {
+		names := make([]string, len(m.opts.Decoders))
+		for i, d := range m.opts.Decoders {
+			names[i] = d.Encoding()
+		}
+		// RFC 7694: advertise the request encodings we do accept.
+		w.Header().Set("Accept-Encoding", strings.Join(names, ", "))
+		writeProblem(w, r, http.StatusUnsupportedMediaType,
+			"unsupported request Content-Encoding", "problem.unsupported_encoding")
+		return false
+	}
+	rc, err := dec.NewReader(r.Body)
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest,
+			"request body does not match its Content-Encoding", "problem.invalid_encoding")
+		return false
+	}
+	r.Body = &decodedBody{
+		ReadCloser: http.MaxBytesReader(w, rc, m.opts.MaxDecompressedBytes),
+		src:        r.Body,
+	}
+	r.Header.Del("Content-Encoding")
+	r.Header.Del("Content-Length")
+	r.ContentLength = -1
+	return true
+}
+
+func writeProblem(w http.ResponseWriter, r *http.Request, status int, detail, detailID string) {
+	httpx.WriteProblemFor(w, r, status, httpx.Problem{
+		Title:    http.StatusText(status),
+		Status:   status,
+		Detail:   detail,
+		DetailID: detailID,
+	})
+}
+
+type decodedBody struct {
+	io.ReadCloser
+	src io.Closer
+}
+
+func (b *decodedBody) Close() error {
+	err := b.ReadCloser.Close()
+	if cerr := b.src.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// negotiate picks the encoder with the highest Accept-Encoding weight,
+// preferring earlier encoders on ties. It returns nil for identity.
+func (m *Middleware) negotiate(accept string) Encoder {
+	if strings.TrimSpace(accept) == "" {
+		return nil
+	}
+	weights := map[string]float64{}
+	for _, part := range strings.Split(accept, ",") {
+		name, params, _ := strings.Cut(part, ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		q := 1.0
+		for _, p := range strings.Split(params, ";") {
+			k, v, ok := strings.Cut(strings.TrimSpace(p), "=")
+			if ok && strings.EqualFold(strings.TrimSpace(k), "q") {
+				if f, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil {
+					q = f
+				}
+			}
+		}
+		weights[name] = q
+	}
+	var best Encoder
+	bestQ := 0.0
+	for _, e := range m.opts.Encoders {
+		q, ok := weights[strings.ToLower(e.Encoding())]
+		if !ok {
+			q = weights["*"]
+		}
+		if q > bestQ {
+			best, bestQ = e, q
+		}
+	}
+	return best
+}
+
+// skip reports whether the media type of ct should be sent uncompressed.
+func (m *Middleware) skip(ct string) bool {
+	mt, _, _ := strings.Cut(ct, ";")
+	mt = strings.ToLower(strings.TrimSpace(mt))
+	if strings.HasSuffix(mt, "+json") || strings.HasSuffix(mt, "+xml") {
+		return false
+	}
+	for _, s := range m.opts.SkipTypes {
+		s = strings.ToLower(s)
+		if mt == s || (strings.HasSuffix(s, "/") && strings.HasPrefix(mt, s)) {
+			return true
+		}
+	}
+	return false
+}
+
+func addVary(h http.Header, field string) {
+	for _, v := range h.Values("Vary") {
+		for _, f := range strings.Split(v, ",") {
+			f = strings.TrimSpace(f)
+			if f == "*" || strings.EqualFold(f, field) {
+				return
+			}
+		}
+	}
+	h.Add("Vary", field)
+}
+
+// compressWriter holds back the status and the first MinSize bytes until
+// it knows whether to compress, then either encodes or passes through.
+type compressWriter struct {
+	http.ResponseWriter
+	m   *Middleware
+	enc Encoder
+	// head makes the writer decide as for GET; once it would encode,
+	// encodedHead discards the body.
+	head        bool
+	encodedHead bool
+	ifNoneMatch string
+
+	status  int
+	buf     []byte
+	decided bool
+	zw      io.WriteCloser
+}
+
+func (w *compressWriter) WriteHeader(code int) {
+	if code < 200 {
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+	if w.status != 0 {
+		return
+	}
+	w.status = code
+	h := w.Header()
+	if code == http.StatusNotModified {
+		// Echo the suffixed ETag the client validated, if that is what
+		// it holds.
+		et := h.Get("ETag")
+		if coded := codedETag(et, w.enc.Encoding()); coded != et && strings.Contains(w.ifNoneMatch, coded) {
+			h.Set("ETag", coded)
+		}
+	}
+	if !compressibleStatus(code) || h.Get("Content-Encoding") != "" {
+		w.passthrough()
+		return
+	}
+	if n, err := strconv.Atoi(h.Get("Content-Length")); err == nil && n < w.m.opts.MinSize {
+		w.passthrough()
+	}
+}
+
+func (w *compressWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.decided {
+		if w.zw != nil {
+			return w.zw.Write(b)
+		}
+		if w.encodedHead {
+			return len(b), nil
+		}
+		return w.ResponseWriter.Write(b)
+	}
+	w.buf = append(w.buf, b...)
+	if len(w.buf) >= w.m.opts.MinSize {
+		if err := w.decide(); err != nil {
+			return 0, err
+		}
+	}
+	return len(b), nil
+}
+
+// Flush commits to a decision regardless of size, since a flushing
+// handler is streaming, then flushes the encoder and the connection.
+func (w *compressWriter) Flush() {
+	if w.status == 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+	if !w.decided {
+		if err := w.decide(); err != nil {
+			return
+		}
+	}
+	if f, ok := w.zw.(interface{ Flush() error }); ok {
+		if err := f.Flush(); err != nil {
+			return
+		}
+	}
+	_ = http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (w *compressWriter) Unwrap() http.ResponseWriter { return w.ResponseWriter }
+
+func (w *compressWriter) decide() error {
+	h := w.Header()
+	ct := h.Get("Content-Type")
+	if ct == "" && len(w.buf) > 0 {
+		ct = http.DetectContentType(w.buf)
+		h.Set("Content-Type", ct)
+	}
+	if w.m.skip(ct) || h.Get("Content-Encoding") != "" {
+		return w.passthrough()
+	}
+	w.decided = true
+	h.Set("Content-Encoding", w.enc.Encoding())
+	h.Del("Content-Length")
+	if et := h.Get("ETag"); et != "" {
+		h.Set("ETag", codedETag(et, w.enc.Encoding()))
+	}
+	w.ResponseWriter.WriteHeader(w.status)
+	buf := w.buf
+	w.buf = nil
+	if w.head {
+		w.encodedHead = true
+		return nil
+	}
+	w.zw = w.enc.NewWriter(w.ResponseWriter)
+	_, err := w.zw.Write(buf)
+	return err
+}
+
+func (w *compressWriter) passthrough() error {
+	w.decided = true
+	w.ResponseWriter.WriteHeader(w.status)
+	if len(w.buf) == 0 {
+		return nil
+	}
+	buf := w.buf
+	w.buf = nil
+	_, err := w.ResponseWriter.Write(buf)
+	return err
+}
+
+// close sends a body that never reached MinSize and finishes the encoder.
+// A HEAD handler usually writes no body, so its Content-Length decides.
+func (w *compressWriter) close() {
+	if !w.decided {
+		if w.status == 0 {
+			return
+		}
+		n, err := strconv.Atoi(w.Header().Get("Content-Length"))
+		if w.head && len(w.buf) == 0 && err == nil && n >= w.m.opts.MinSize {
+			_ = w.decide()
+		} else {
+			_ = w.passthrough()
+		}
+	}
+	if w.zw != nil {
+		_ = w.zw.Close()
+	}
+}
+
+// codedETag appends the content coding to a strong ETag, turning "abc"
+// into "abc-gzip". Weak ETags already allow a different encoding and are
+// returned unchanged, as are tags that carry the suffix.
+func codedETag(etag, coding string) string {
+	if len(etag) < 2 || etag[0] != '"' || etag[len(etag)-1] != '"' {
+		return etag
+	}
+	suffix := "-" + coding + `"`
+	if strings.HasSuffix(etag, suffix) {
+		return etag
+	}
+	return etag[:len(etag)-1] + suffix
+}
+
+func compressibleStatus(code int) bool {
+	switch code {
+	case http.StatusNoContent, http.StatusPartialContent, http.StatusNotModified:
+		return false
+	}
+	return true
+}
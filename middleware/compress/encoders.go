@@ -0,0 +1,88 @@
+package compress
+
+import (
+	"compress/flate"
+	"compress/gzip"
+	"io"
+	"sync"
+)
+
+// Encoder produces one response Content-Encoding.
+type Encoder interface {
+	// Encoding is the Content-Encoding token, e.g. "gzip".
+	Encoding() string
+	// NewWriter compresses into w. The writer may implement
+	// Flush() error, which streaming responses need.
+	NewWriter(w io.Writer) io.WriteCloser
+}
+
+// Decoder reads one request Content-Encoding.
+type Decoder interface {
+	Encoding() string
+	NewReader(r io.Reader) (io.ReadCloser, error)
+}
+
+// Gzip encodes and decodes "gzip". Level defaults to
+// gzip.DefaultCompression.
+type Gzip struct {
+	Level int
+}
+
+var gzipPools sync.Map // level -> *sync.Pool
+
+func (Gzip) Encoding() string { return "gzip" }
+
+func (g Gzip) NewWriter(w io.Writer) io.WriteCloser {
+	level := g.Level
+	if level == 0 {
+		level = gzip.DefaultCompression
+	}
+	p, _ := gzipPools.LoadOrStore(level, &sync.Pool{})
+	pool := p.(*sync.Pool)
+	zw, ok := pool.Get().(*gzip.Writer)
+	if ok {
+		zw.Reset(w)
+	} else {
+		zw, _ = gzip.NewWriterLevel(w, level)
+		if zw == nil {
+			zw = gzip.NewWriter(w)
+		}
+	}
+	return &pooledGzip{Writer: zw, pool: pool}
+}
+
+func (Gzip) NewReader(r io.Reader) (io.ReadCloser, error) { return gzip.NewReader(r) }
+
+type pooledGzip struct {
+	*gzip.Writer
+	pool *sync.Pool
+}
+
+func (p *pooledGzip) Close() error {
+	err := p.Writer.Close()
+	p.pool.Put(p.Writer)
+	return err
+}
+
+// Deflate encodes and decodes "deflate" (zlib-wrapped, as HTTP specifies;
+// raw deflate is accepted when reading). Level defaults to
+// flate.DefaultCompression.
+type Deflate struct {
+	Level int
+}
+
+func (Deflate) Encoding() string { return "deflate" }
+
+func (d Deflate) NewWriter(w io.Writer) io.WriteCloser {
+	level := d.Level
+	if level == 0 {
+		level = flate.DefaultCompression
+	}
+	zw, err := newZlibWriter(w, level)
+	if err != nil {
+		zw, _ = newZlibWriter(w, flate.DefaultCompression)
+	}
+	return zw
+}
+
+func (Deflate) NewReader(r io.Reader) (io.ReadCloser, error) { return newZlibOrRawReader(r) }
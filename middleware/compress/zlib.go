@@ -0,0 +1,27 @@
+package compress
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/zlib"
+	"io"
+)
+
+func newZlibWriter(w io.Writer, level int) (io.WriteCloser, error) {
+	return zlib.NewWriterLevel(w, level)
+}
+
+// newZlibOrRawReader accepts both zlib-wrapped and raw deflate bodies,
+// since clients disagree on what "deflate" means.
+func newZlibOrRawReader(r io.Reader) (io.ReadCloser, error) {
+	br := bufio.NewReader(r)
+	hdr, err := br.Peek(2)
+	if err == nil && isZlibHeader(hdr) {
+		return zlib.NewReader(br)
+	}
+	return flate.NewReader(br), nil
+}
+
+func isZlibHeader(b []byte) bool {
+	return b[0]&0x0f == 8 && (uint16(b[0])<<8|uint16(b[1]))%31 == 0
+}
@@ -0,0 +1,115 @@
+package concurrency
+
+import (
+	"math"
+	"time"
+)
+
+// Sample describes one completed request.
+type Sample struct {
+	// RTT is how long the handler ran, excluding time spent queued.
+	RTT time.Duration
+	// InFlight is the number of requests in flight when it started.
+	InFlight int
+	// Dropped reports overload: a 503 or 504 from the handler, or a
+	// request whose context deadline expired.
+	Dropped bool
+}
+
+// Algorithm adapts the concurrency limit from completed requests. Update
+// is called with the current limit and returns the new one; calls are
+// serialized, so implementations may keep unsynchronized state.
+type Algorithm interface {
+	Update(limit float64, s Sample) float64
+}
+
+// AIMD grows the limit by one per successful request while the limiter
+// is saturated and multiplies it by Backoff on a drop or when RTT exceeds
+// Timeout.
+type AIMD struct {
+	Min, Max int           // bounds; default 1 and 1000
+	Backoff  float64       // default 0.9
+	Timeout  time.Duration // default 5s
+}
+
+func (a *AIMD) Update(limit float64, s Sample) float64 {
+	backoff := a.Backoff
+	if backoff <= 0 || backoff >= 1 {
+		backoff = 0.9
+	}
+	timeout := a.Timeout
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+	switch {
+	case s.Dropped || s.RTT > timeout:
+		limit *= backoff
+	case float64(s.InFlight) >= limit/2:
+		// Only grow when the limit is actually being used.
+		limit++
+	}
+	return clamp(limit, a.Min, a.Max)
+}
+
+// Gradient compares the latest RTT with a slowly moving long-term
+// average: when latency rises above Tolerance times the baseline the
+// limit shrinks proportionally, otherwise it grows by about sqrt(limit).
+// It follows the gradient2 algorithm from Netflix concurrency-limits.
+type Gradient struct {
+	Min, Max int // bounds; default 1 and 1000
+	// Tolerance is the latency ratio accepted before shrinking.
+	// Default 1.5.
+	Tolerance float64
+	// Smoothing weights each new limit estimate. Default 0.2.
+	Smoothing float64
+	// Window is the number of samples averaged into the long-term RTT.
+	// Default 600.
+	Window int
+
+	longRTT float64 // seconds, exponential moving average
+}
+
+func (g *Gradient) Update(limit float64, s Sample) float64 {
+	tolerance := g.Tolerance
+	if tolerance <= 0 {
+		tolerance = 1.5
+	}
+	smoothing := g.Smoothing
+	if smoothing <= 0 || smoothing > 1 {
+		smoothing = 0.2
+	}
+	window := g.Window
+	if window <= 0 {
+		window = 600
+	}
+	rtt := s.RTT.Seconds()
+	if rtt <= 0 {
+		return limit
+	}
+	if g.longRTT == 0 {
+		g.longRTT = rtt
+	} else {
+		g.longRTT += (rtt - g.longRTT) / float64(window)
+	}
+	gradient := math.Max(0.5, math.Min(1, tolerance*g.longRTT/rtt))
+	if s.Dropped {
+		gradient = 0.5
+	}
+	next := limit*gradient + math.Sqrt(limit)
+	next = limit*(1-smoothing) + next*smoothing
+	// A mostly idle limiter has no evidence that a higher limit is safe.
+	if next > limit && float64(s.InFlight) < limit/2 {
+		return limit
+	}
+	return clamp(next, g.Min, g.Max)
+}
+
+func clamp(limit float64, lo, hi int) float64 {
+	if lo <= 0 {
+		lo = 1
+	}
+	if hi <= 0 {
+		hi = 1000
+	}
+	return math.Max(float64(lo), math.Min(float64(hi), limit))
+}
@@ -0,0 +1,262 @@
+package concurrency
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/aatuh/api-toolkit/clock"
+	"github.com/aatuh/api-toolkit/httpx"
+	"github.com/aatuh/api-toolkit/middleware/metrics"
+	"github.com/aatuh/api-toolkit/ports"
+)
+
+// Priority orders queued requests: higher classes are admitted first
+// and may displace lower ones from a full queue.
+type Priority int
+
+const (
+	Low Priority = iota
+	Normal
+	High
+)
+
+const numPriorities = 3
+
+// Options configures the concurrency limiter.
+type Options struct {
+	// Limit caps in-flight requests, or is the starting point when
+	// Algorithm is set. Defaults to 100.
+	Limit int
+	// Algorithm adapts the limit from observed latency, e.g. &AIMD{} or
+	// &Gradient{}. Nil keeps Limit fixed.
+	Algorithm Algorithm
+	// MaxQueue is how many requests may wait for a slot. Zero sheds as
+	// soon as the limit is reached.
+	MaxQueue int
+	// MaxWait bounds the time spent queued. Defaults to 1s.
+	MaxWait time.Duration
+	// Classify assigns a priority; nil treats every request as Normal.
+	Classify func(r *http.Request) Priority
+	// RetryAfter is sent with shed responses. Defaults to 1s.
+	RetryAfter time.Duration
+	// Metrics receives concurrency_inflight, concurrency_limit and
+	// concurrency_queued gauges (when it implements metrics.GaugeRecorder)
+	// and the concurrency_shed_total counter, labelled limiter=<Name>.
+	Metrics metrics.MetricsRecorder
+	// Name defaults to "default".
+	Name  string
+	Clock ports.Clock
+}
+
+type Middleware struct {
+	opts Options
+
+	mu       sync.Mutex
+	limit    float64
+	inflight int
+	queued   int
+	queues   [numPriorities]list.List // of *waiter, oldest first
+}
+
+type waiter struct {
+	ready   chan struct{}
+	granted bool // admitted; otherwise shed when ready closes
+	prio    Priority
+	el      *list.Element
+}
+
+// New returns the concurrency limiting middleware. Requests over the
+// limit wait in a bounded priority queue for up to MaxWait, then are shed
+// with 503 and Retry-After.
+func New(opts Options) *Middleware {
+	if opts.Limit <= 0 {
+		opts.Limit = 100
+	}
+	if opts.MaxWait <= 0 {
+		opts.MaxWait = time.Second
+	}
+	if opts.RetryAfter <= 0 {
+		opts.RetryAfter = time.Second
+	}
+	if opts.Metrics == nil {
+		opts.Metrics = metrics.NoopMetrics{}
+	}
+	if opts.Name == "" {
+		opts.Name = "default"
+	}
+	opts.Clock = clock.OrSystem(opts.Clock)
+	return &Middleware{opts: opts, limit: float64(opts.Limit)}
+}
+
+// Limit returns the current limit.
+func (m *Middleware) Limit() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return int(m.limit)
+}
+
+func (m *Middleware) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		prio := Normal
+		if m.opts.Classify != nil {
+			prio = min(max(m.opts.Classify(r), Low), High)
+		}
+		inflight, reason := m.acquire(r.Context(), prio)
+		if reason != "" {
+			m.shed(w, r, reason)
+			return
+		}
+		start := m.opts.Clock.Now()
+		sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+		defer func() {
+			m.release(Sample{
+				RTT:      m.opts.Clock.Since(start),
+				InFlight: inflight,
+				Dropped: sw.status == http.StatusServiceUnavailable ||
+					sw.status == http.StatusGatewayTimeout ||
+					errors.Is(r.Context().Err(), context.DeadlineExceeded),
+			})
+		}()
+		next.ServeHTTP(sw, r)
+	})
+}
+
+// acquire admits the request, returning the in-flight count including
+// it, or the reason it was shed.
+func (m *Middleware) acquire(ctx context.Context, prio Priority) (int, string) {
+	m.mu.Lock()
+	if m.inflight < int(m.limit) && m.queued == 0 {
+		m.inflight++
+		n := m.inflight
+		m.mu.Unlock()
+		m.report()
+		return n, ""
+	}
+	if m.queued >= m.opts.MaxQueue && !m.evictBelow(prio) {
+		m.mu.Unlock()
+		return 0, "queue_full"
+	}
+	w := &waiter{ready: make(chan struct{}), prio: prio}
+	w.el = m.queues[prio].PushBack(w)
+	m.queued++
+	m.mu.Unlock()
+	m.report()
+
+	t := m.opts.Clock.NewTimer(m.opts.MaxWait)
+	defer t.Stop()
+	reason := ""
+	select {
+	case <-w.ready:
+	case <-t.C():
+		reason = "timeout"
+	case <-ctx.Done():
+		reason = "canceled"
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	select {
+	case <-w.ready:
+		// Admitted or evicted, possibly racing with the timer.
+		if w.granted {
+			return m.inflight, ""
+		}
+		return 0, "displaced"
+	default:
+	}
+	m.queues[prio].Remove(w.el)
+	m.queued--
+	return 0, reason
+}
+
+// evictBelow sheds the newest waiter of the lowest priority under prio
+// to make room. Callers hold m.mu.
+func (m *Middleware) evictBelow(prio Priority) bool {
+	for p := Low; p < prio; p++ {
+		if el := m.queues[p].Back(); el != nil {
+			w := m.queues[p].Remove(el).(*waiter)
+			m.queued--
+			close(w.ready)
+			return true
+		}
+	}
+	return false
+}
+
+func (m *Middleware) release(s Sample) {
+	m.mu.Lock()
+	m.inflight--
+	if m.opts.Algorithm != nil {
+		m.limit = m.opts.Algorithm.Update(m.limit, s)
+	}
+	for p := High; p >= Low && m.inflight < int(m.limit); p-- {
+		for m.inflight < int(m.limit) {
+			el := m.queues[p].Front()
+			if el == nil {
+				break
+			}
+			w := m.queues[p].Remove(el).(*waiter)
+			m.queued--
+			m.inflight++
+			w.granted = true
+			close(w.ready)
+		}
+	}
+	m.mu.Unlock()
+	m.report()
+}
+
+func (m *Middleware) report() {
+	m.mu.Lock()
+	inflight, limit, queued := m.inflight, int(m.limit), m.queued
+	m.mu.Unlock()
+	labels := metrics.Labels{"limiter": m.opts.Name}
+	metrics.SetGauge(m.opts.Metrics, "concurrency_inflight", float64(inflight), labels)
+	metrics.SetGauge(m.opts.Metrics, "concurrency_limit", float64(limit), labels)
+	metrics.SetGauge(m.opts.Metrics, "concurrency_queued", float64(queued), labels)
+}
+
+func (m *Middleware) shed(w http.ResponseWriter, r *http.Request, reason string) {
+	m.opts.Metrics.IncCounter("concurrency_shed_total",
+		metrics.Labels{"limiter": m.opts.Name, "reason": reason})
+	if reason == "canceled" {
+		// The client is gone; nobody reads the response.
+		return
+	}
+	secs := int((m.opts.RetryAfter + time.Second - 1) / time.Second)
+	w.Header().Set("Retry-After", strconv.Itoa(secs))
+	httpx.WriteProblemFor(w, r, http.StatusServiceUnavailable, httpx.Problem{
+		Title:    http.StatusText(http.StatusServiceUnavailable),
+		Status:   http.StatusServiceUnavailable,
+		Detail:   "server is overloaded; retry later",
+		DetailID: "problem.overloaded",
+	})
+}
+
+type statusWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (w *statusWriter) WriteHeader(code int) {
+	if !w.wroteHeader && code >= 200 {
+		w.status = code
+		w.wroteHeader = true
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+// Flush passes flushes through so streaming responses work behind the
+// middleware.
+func (w *statusWriter) Flush() {
+	_ = http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (w *statusWriter) Unwrap() http.ResponseWriter { return w.ResponseWriter }
@@ -0,0 +1,212 @@
+package conditional
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/aatuh/api-toolkit/httpx"
+)
+
+// Options configures the conditional request middleware.
+type Options struct {
+	// MaxBuffer caps how much of a GET/HEAD response is buffered to
+	// compute an ETag. Larger or flushed responses are streamed
+	// unchanged. Defaults to 1 MiB.
+	MaxBuffer int
+	// Require selects requests that must carry If-Match; those without
+	// it get 428 Precondition Required. Nil requires nothing; see
+	// UnsafeMethods.
+	Require func(r *http.Request) bool
+}
+
+type Middleware struct {
+	opts Options
+}
+
+// New returns the conditional request middleware. For GET and HEAD it
+// buffers successful responses, uses the handler's ETag header or a
+// strong ETag hashed from the body (HEAD handlers that write no body keep
+// their own ETag and Content-Length, if any), and answers If-None-Match,
+// If-Modified-Since (against the handler's Last-Modified) and If-Match
+// with 304 or 412. Handlers of writes check If-Match themselves with
+// CheckETag or CheckVersion.
+func New(opts Options) *Middleware {
+	if opts.MaxBuffer <= 0 {
+		opts.MaxBuffer = 1 << 20
+	}
+	return &Middleware{opts: opts}
+}
+
+// UnsafeMethods matches PUT, PATCH and DELETE, for Options.Require.
+func UnsafeMethods(r *http.Request) bool {
+	switch r.Method {
+	case http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+func (m *Middleware) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if m.opts.Require != nil && m.opts.Require(r) && r.Header.Get("If-Match") == "" {
+			httpx.WriteError(w, r, ErrPreconditionRequired)
+			return
+		}
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			next.ServeHTTP(w, r)
+			return
+		}
+		bw := &bufferedWriter{ResponseWriter: w, max: m.opts.MaxBuffer}
+		next.ServeHTTP(bw, r)
+		if bw.passthrough {
+			return
+		}
+		m.finish(bw, r)
+	})
+}
+
+func (m *Middleware) finish(bw *bufferedWriter, r *http.Request) {
+	w := bw.ResponseWriter
+	h := w.Header()
+	// A HEAD handler that writes no body leaves nothing to hash or
+	// measure; hashing the empty buffer would disagree with GET.
+	bodyless := r.Method == http.MethodHead && bw.buf.Len() == 0
+	etag := h.Get("ETag")
+	if etag == "" && !bodyless {
+		etag = HashETag(bw.buf.Bytes())
+		h.Set("ETag", etag)
+	}
+	var lastModified time.Time
+	if lm := h.Get("Last-Modified"); lm != "" {
+		lastModified, _ = http.ParseTime(lm)
+	}
+	if err := checkRead(r, etag, lastModified); err != nil {
+		if err == errNotModified {
+			writeNotModified(w)
+			return
+		}
+		httpx.WriteError(w, r, err)
+		return
+	}
+	if !bodyless {
+		h.Set("Content-Length", strconv.Itoa(bw.buf.Len()))
+	}
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(bw.buf.Bytes())
+}
+
+// CheckNotModified lets a handler skip building a response: when r's
+// If-None-Match or If-Modified-Since show the client's copy is current it
+// writes 304 (with etag) and returns true. Either validator may be empty.
+func CheckNotModified(w http.ResponseWriter, r *http.Request, etag string, lastModified time.Time) bool {
+	if etag != "" {
+		w.Header().Set("ETag", etag)
+	}
+	if !lastModified.IsZero() {
+		w.Header().Set("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	}
+	if checkRead(r, etag, lastModified) == errNotModified {
+		writeNotModified(w)
+		return true
+	}
+	return false
+}
+
+// HashETag returns a strong ETag derived from body.
+func HashETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + base64.RawURLEncoding.EncodeToString(sum[:18]) + `"`
+}
+
+// checkRead evaluates GET/HEAD preconditions in RFC 9110 order.
+func checkRead(r *http.Request, etag string, lastModified time.Time) error {
+	if im := r.Header.Get("If-Match"); im != "" && etag != "" && !matches(im, etag, true) {
+		return ErrPreconditionFailed
+	}
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		if etag != "" && matches(inm, etag, false) {
+			return errNotModified
+		}
+		return nil
+	}
+	if ims := r.Header.Get("If-Modified-Since"); ims != "" && !lastModified.IsZero() {
+		t, err := http.ParseTime(ims)
+		if err == nil && !lastModified.Truncate(time.Second).After(t) {
+			return errNotModified
+		}
+	}
+	return nil
+}
+
+func writeNotModified(w http.ResponseWriter) {
+	h := w.Header()
+	h.Del("Content-Type")
+	h.Del("Content-Length")
+	w.WriteHeader(http.StatusNotModified)
+}
+
+// bufferedWriter holds a 200 response until it is complete. Other
+// statuses, flushes and oversized bodies switch it to pass-through.
+type bufferedWriter struct {
+	http.ResponseWriter
+	max         int
+	buf         bytes.Buffer
+	wroteHeader bool
+	passthrough bool
+}
+
+func (w *bufferedWriter) WriteHeader(code int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	if code != http.StatusOK {
+		w.passthrough = true
+		w.ResponseWriter.WriteHeader(code)
+	}
+}
+
+func (w *bufferedWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.passthrough {
+		return w.ResponseWriter.Write(b)
+	}
+	if w.buf.Len()+len(b) > w.max {
+		if err := w.release(); err != nil {
+			return 0, err
+		}
+		return w.ResponseWriter.Write(b)
+	}
+	return w.buf.Write(b)
+}
+
+// Flush gives up on an ETag so streaming handlers keep working.
+func (w *bufferedWriter) Flush() {
+	if !w.passthrough {
+		if !w.wroteHeader {
+			w.WriteHeader(http.StatusOK)
+		}
+		_ = w.release()
+	}
+	_ = http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (w *bufferedWriter) Unwrap() http.ResponseWriter { return w.ResponseWriter }
+
+func (w *bufferedWriter) release() error {
+	if w.passthrough {
+		return nil
+	}
+	w.passthrough = true
+	w.ResponseWriter.WriteHeader(http.StatusOK)
+	_, err := w.ResponseWriter.Write(w.buf.Bytes())
+	w.buf.Reset()
+	return err
+}
@@ -0,0 +1,174 @@
+package conditional
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/aatuh/api-toolkit/httpx"
+	"github.com/aatuh/api-toolkit/txpostgres"
+)
+
+// PreconditionError is a failed or missing precondition. It renders as a
+// Problem through httpx.WriteError.
+type PreconditionError struct {
+	Status   int
+	Detail   string
+	DetailID string
+}
+
+func (e *PreconditionError) Error() string { return e.Detail }
+
+// Problem implements httpx.ProblemError.
+func (e *PreconditionError) Problem() httpx.Problem {
+	return httpx.Problem{
+		Title:    http.StatusText(e.Status),
+		Status:   e.Status,
+		Detail:   e.Detail,
+		DetailID: e.DetailID,
+	}
+}
+
+var (
+	// ErrPreconditionFailed reports an If-Match (or If-None-Match on a
+	// write) that does not hold: the resource changed. 412.
+	ErrPreconditionFailed = &PreconditionError{
+		Status:   http.StatusPreconditionFailed,
+		Detail:   "the resource has been modified; fetch it again and retry",
+		DetailID: "problem.precondition_failed",
+	}
+	// ErrPreconditionRequired reports a write without If-Match on a route
+	// that requires one. 428.
+	ErrPreconditionRequired = &PreconditionError{
+		Status:   http.StatusPreconditionRequired,
+		Detail:   "this request must be conditional; send If-Match with the resource's ETag",
+		DetailID: "problem.precondition_required",
+	}
+
+	errNotModified = errors.New("not modified")
+)
+
+// CheckETag evaluates r's If-Match against the resource's current strong
+// ETag before a write. It returns nil when the header is absent, "*" or
+// lists current, and ErrPreconditionFailed otherwise. Use required to
+// turn a missing header into ErrPreconditionRequired.
+func CheckETag(r *http.Request, current string, required bool) error {
+	im := r.Header.Get("If-Match")
+	if im == "" {
+		if required {
+			return ErrPreconditionRequired
+		}
+		return nil
+	}
+	if !matches(im, current, true) {
+		return ErrPreconditionFailed
+	}
+	return nil
+}
+
+// VersionETag formats a row version as a strong ETag, e.g. `"7"`.
+func VersionETag(version int64) string {
+	return `"` + strconv.FormatInt(version, 10) + `"`
+}
+
+// SetVersion sets the ETag response header for version.
+func SetVersion(w http.ResponseWriter, version int64) {
+	w.Header().Set("ETag", VersionETag(version))
+}
+
+// CheckVersion is CheckETag for integer row versions.
+func CheckVersion(r *http.Request, current int64, required bool) error {
+	return CheckETag(r, VersionETag(current), required)
+}
+
+// CheckRowVersion runs a single-row version query inside the current
+// txpostgres transaction and checks it against If-Match, so the update
+// that follows only happens on the version the client saw. Lock the row
+// to keep it stable until commit:
+//
+//	err := tx.WithinTx(ctx, func(ctx context.Context) error {
+//		db := txpostgres.FromCtx(ctx, pool)
+//		v, err := conditional.CheckRowVersion(ctx, db, r, true,
+//			`SELECT version FROM items WHERE id = $1 FOR UPDATE`, id)
+//		if err != nil {
+//			return err // 404, 412 or 428 through httpx.WriteError
+//		}
+//		_, err = db.Exec(ctx, `UPDATE items SET name = $2, version = $3 WHERE id = $1`, id, name, v+1)
+//		return err
+//	})
+func CheckRowVersion(ctx context.Context, db txpostgres.DBer, r *http.Request, required bool, query string, args ...any) (int64, error) {
+	if required && r.Header.Get("If-Match") == "" {
+		return 0, ErrPreconditionRequired
+	}
+	var version int64
+	if err := db.QueryRow(ctx, query, args...).Scan(&version); err != nil {
+		return 0, err
+	}
+	return version, CheckVersion(r, version, required)
+}
+
+// ContentCodings are the codings whose ETag suffix, added by
+// middleware/compress to encoded responses ("abc-gzip"), is ignored when
+// a client's tag is compared with the resource's. Add the names of
+// custom compress.Encoders.
+var ContentCodings = []string{"gzip", "deflate", "br", "zstd"}
+
+// matches reports whether the entity-tag list in header contains etag.
+// Strong comparison ignores weak tags; "*" matches anything.
+func matches(header, etag string, strong bool) bool {
+	if strings.TrimSpace(header) == "*" {
+		return true
+	}
+	if strong && strings.HasPrefix(etag, "W/") {
+		return false
+	}
+	want := strings.TrimPrefix(etag, "W/")
+	for _, tag := range parseETags(header) {
+		weak := strings.HasPrefix(tag, "W/")
+		if strong && weak {
+			continue
+		}
+		tag = strings.TrimPrefix(tag, "W/")
+		if tag == want || stripCoding(tag) == want {
+			return true
+		}
+	}
+	return false
+}
+
+// stripCoding removes a ContentCodings suffix from a quoted tag.
+func stripCoding(tag string) string {
+	for _, c := range ContentCodings {
+		if suffix := "-" + c + `"`; strings.HasSuffix(tag, suffix) {
+			return tag[:len(tag)-len(suffix)] + `"`
+		}
+	}
+	return tag
+}
+
+// parseETags splits an entity-tag list, respecting quotes.
+func parseETags(header string) []string {
+	var tags []string
+	s := header
+	for {
+		s = strings.TrimLeft(s, " \t,")
+		if s == "" {
+			return tags
+		}
+		prefix := ""
+		if strings.HasPrefix(s, "W/") {
+			prefix, s = "W/", s[2:]
+		}
+		if !strings.HasPrefix(s, `"`) {
+			return tags // malformed; stop
+		}
+		end := strings.IndexByte(s[1:], '"')
+		if end < 0 {
+			return tags
+		}
+		tags = append(tags, prefix+s[:end+2])
+		s = s[end+2:]
+	}
+}
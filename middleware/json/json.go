@@ -3,36 +3,83 @@ package jsonmw
 import (
 	"encoding/json"
 	"errors"
+	"mime"
 	"net/http"
+	"sort"
 	"strings"
+
+	"github.com/aatuh/api-toolkit/httpx"
 )
 
 type Middleware struct {
 	RequireJSON bool
+	// Allow lists media types accepted besides JSON, keyed by path
+	// prefix matched on segment boundaries; the longest matching prefix
+	// applies and "" matches every path. Entries may be "type/*" wildcards. See AllowTypes.
+	Allow map[string][]string
 }
 
 func New(require bool) *Middleware { return &Middleware{RequireJSON: require} }
 
+// AllowTypes accepts the given media types, e.g. "multipart/form-data",
+// for requests whose path starts with prefix.
+func (m *Middleware) AllowTypes(prefix string, types ...string) *Middleware {
+	if m.Allow == nil {
+		m.Allow = make(map[string][]string)
+	}
+	m.Allow[prefix] = append(m.Allow[prefix], types...)
+	return m
+}
+
+// Handler rejects requests that carry a body whose Content-Type is
+// neither JSON (application/json or any +json type) nor allowed for the
+// path. Requests without a body, such as most GETs and DELETEs, pass.
+// Rejections are 415 Problems listing the accepted types, with an
+// Accept-Post or Accept-Patch hint for POST and PATCH.
 func (m *Middleware) Handler(next http.Handler) http.Handler {
 	if !m.RequireJSON {
 		return next
 	}
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		ct := r.Header.Get("Content-Type")
-		if ct == "" {
-			http.Error(w, "missing content-type", http.StatusUnsupportedMediaType)
+		if !hasBody(r) {
+			next.ServeHTTP(w, r)
 			return
 		}
-		if !isJSON(ct) {
-			http.Error(w, "content-type must be application/json",
-				http.StatusUnsupportedMediaType)
+		allowed := m.allowed(r.URL.Path)
+		mt, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if err == nil && (isJSON(mt) || matches(allowed, mt)) {
+			next.ServeHTTP(w, r)
 			return
 		}
-		next.ServeHTTP(w, r)
+		accepted := append([]string{"application/json"}, allowed...)
+		switch r.Method {
+		case http.MethodPost:
+			w.Header().Set("Accept-Post", strings.Join(accepted, ", "))
+		case http.MethodPatch:
+			w.Header().Set("Accept-Patch", strings.Join(accepted, ", "))
+		}
+		p := httpx.Problem{
+			Title:    http.StatusText(http.StatusUnsupportedMediaType),
+			Status:   http.StatusUnsupportedMediaType,
+			Detail:   "request Content-Type is not supported",
+			DetailID: "problem.unsupported_media_type",
+		}
+		p.With("accepted", accepted)
+		httpx.WriteProblemFor(w, r, http.StatusUnsupportedMediaType, p)
 	})
 }
 
+// Decode strictly decodes a JSON body of at most maxBytes into dst; see
+// httpx.DecodeJSONLimit. Errors render through httpx.WriteError as 400
+// Problems naming the field, line and column, or 413 when too large.
+func Decode(r *http.Request, dst any, maxBytes int64) error {
+	return httpx.DecodeJSONLimit(r, dst, maxBytes)
+}
+
 // StrictDecoder creates a JSON decoder that disallows unknown fields.
+//
+// Deprecated: use Decode or httpx.DecodeJSON, which also reject trailing
+// data, enforce a size limit and report where decoding failed.
 func StrictDecoder(r *http.Request) (*json.Decoder, error) {
 	if r.Body == nil {
 		return nil, errors.New("empty body")
@@ -42,8 +89,45 @@ func StrictDecoder(r *http.Request) (*json.Decoder, error) {
 	return dec, nil
 }
 
-func isJSON(ct string) bool {
-	ct = strings.ToLower(ct)
-	return strings.Contains(ct, "application/json") ||
-		strings.HasSuffix(ct, "+json")
+func (m *Middleware) allowed(path string) []string {
+	best := -1
+	var types []string
+	for prefix, t := range m.Allow {
+		if underPrefix(path, prefix) && len(prefix) > best {
+			best, types = len(prefix), t
+		}
+	}
+	out := append([]string(nil), types...)
+	sort.Strings(out)
+	return out
+}
+
+// underPrefix reports whether path is prefix or lies below it, matching
+// on segment boundaries so "/uploads" does not cover "/uploads-admin".
+func underPrefix(path, prefix string) bool {
+	base := strings.TrimSuffix(prefix, "/")
+	return path == prefix || path == base || strings.HasPrefix(path, base+"/")
+}
+
+// hasBody reports whether r carries a body: a positive Content-Length or
+// one of unknown length, such as a chunked upload.
+func hasBody(r *http.Request) bool {
+	if r.Body == nil || r.Body == http.NoBody {
+		return false
+	}
+	return r.ContentLength != 0
+}
+
+func matches(allowed []string, mt string) bool {
+	for _, a := range allowed {
+		a = strings.ToLower(a)
+		if a == mt || (strings.HasSuffix(a, "/*") && strings.HasPrefix(mt, a[:len(a)-1])) {
+			return true
+		}
+	}
+	return false
+}
+
+func isJSON(mt string) bool {
+	return mt == "application/json" || strings.HasSuffix(mt, "+json")
 }
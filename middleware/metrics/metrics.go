@@ -1,9 +1,15 @@
 package metrics
 
 import (
+	"errors"
 	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/aatuh/api-toolkit/logctx"
+	"github.com/aatuh/api-toolkit/ports"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
@@ -18,6 +24,20 @@ type MetricsRecorder interface {
 	ObserveHistogram(name string, value float64, labels Labels)
 }
 
+// GaugeRecorder is an optional extension of MetricsRecorder for values
+// that go up and down. Use SetGauge to record through any recorder.
+type GaugeRecorder interface {
+	SetGauge(name string, value float64, labels Labels)
+}
+
+// SetGauge records value if m implements GaugeRecorder and does nothing
+// otherwise.
+func SetGauge(m MetricsRecorder, name string, value float64, labels Labels) {
+	if g, ok := m.(GaugeRecorder); ok {
+		g.SetGauge(name, value, labels)
+	}
+}
+
 // PrometheusHandler returns a standard /metrics http.Handler if the
 // Prometheus client is linked; otherwise returns http.NotFoundHandler.
 // This indirection avoids hard dependency on the Prometheus client.
@@ -30,6 +50,7 @@ type NoopMetrics struct{}
 
 func (NoopMetrics) IncCounter(_ string, _ Labels)                  {}
 func (NoopMetrics) ObserveHistogram(_ string, _ float64, _ Labels) {}
+func (NoopMetrics) SetGauge(_ string, _ float64, _ Labels)         {}
 
 // Middleware instruments HTTP traffic using a provided recorder.
 type Middleware struct {
@@ -45,10 +66,35 @@ func (mw *Middleware) HandlerFunc() func(http.Handler) http.Handler {
 }
 
 // PrometheusRecorder implements MetricsRecorder using Prometheus client.
-// This is a minimal adapter; applications can supply their own recorder.
+// The server metrics (http_requests_total and
+// http_request_duration_seconds) use fixed method/route/status labels.
+// Any other name, and every gauge, gets its own vector on first use,
+// labelled by the keys of that first call. Later calls with a different
+// label set are dropped and logged once per metric, as is a name that
+// conflicts with a collector already in the registerer.
 type PrometheusRecorder struct {
+	// Help supplies HELP texts by metric name for vectors created on
+	// first use; names without an entry use the name itself. Set it
+	// before recording.
+	Help map[string]string
+	// Log receives registration conflicts and label-set mismatches.
+	Log ports.Logger
+
 	requests  *prometheus.CounterVec
 	durations *prometheus.HistogramVec
+
+	reg        prometheus.Registerer
+	buckets    []float64
+	mu         sync.Mutex
+	counters   map[string]*labelledVec[*prometheus.CounterVec]
+	histograms map[string]*labelledVec[*prometheus.HistogramVec]
+	gauges     map[string]*labelledVec[*prometheus.GaugeVec]
+}
+
+type labelledVec[V any] struct {
+	vec    V
+	keys   []string
+	warned atomic.Bool
 }
 
 // NewPrometheusRecorder wires counters and histograms with standard names.
@@ -73,23 +119,156 @@ func NewPrometheusRecorder(registerer prometheus.Registerer, buckets []float64)
 			Help:    "HTTP request duration in seconds",
 			Buckets: buckets,
 		}, []string{"method", "route", "status"}),
+		reg:        reg,
+		buckets:    buckets,
+		counters:   map[string]*labelledVec[*prometheus.CounterVec]{},
+		histograms: map[string]*labelledVec[*prometheus.HistogramVec]{},
+		gauges:     map[string]*labelledVec[*prometheus.GaugeVec]{},
 	}
 }
 
-func (p *PrometheusRecorder) IncCounter(_ string, labels Labels) {
+func (p *PrometheusRecorder) IncCounter(name string, labels Labels) {
 	if p == nil || p.requests == nil {
 		return
 	}
-	method, route, status := sanitizeHTTPLabels(labels)
-	p.requests.WithLabelValues(method, route, status).Inc()
+	if name == "" || name == "http_requests_total" {
+		method, route, status := sanitizeHTTPLabels(labels)
+		p.requests.WithLabelValues(method, route, status).Inc()
+		return
+	}
+	c := p.counter(name, labels)
+	if vals, ok := labelValues(p, name, c, labels); ok {
+		c.vec.WithLabelValues(vals...).Inc()
+	}
 }
 
-func (p *PrometheusRecorder) ObserveHistogram(_ string, value float64, labels Labels) {
+func (p *PrometheusRecorder) ObserveHistogram(name string, value float64, labels Labels) {
 	if p == nil || p.durations == nil {
 		return
 	}
-	method, route, status := sanitizeHTTPLabels(labels)
-	p.durations.WithLabelValues(method, route, status).Observe(value)
+	if name == "" || name == "http_request_duration_seconds" {
+		method, route, status := sanitizeHTTPLabels(labels)
+		p.durations.WithLabelValues(method, route, status).Observe(value)
+		return
+	}
+	h := p.histogram(name, labels)
+	if vals, ok := labelValues(p, name, h, labels); ok {
+		h.vec.WithLabelValues(vals...).Observe(value)
+	}
+}
+
+// SetGauge implements GaugeRecorder.
+func (p *PrometheusRecorder) SetGauge(name string, value float64, labels Labels) {
+	if p == nil || p.gauges == nil {
+		return
+	}
+	g := p.gauge(name, labels)
+	if vals, ok := labelValues(p, name, g, labels); ok {
+		g.vec.WithLabelValues(vals...).Set(value)
+	}
+}
+
+func (p *PrometheusRecorder) counter(name string, labels Labels) *labelledVec[*prometheus.CounterVec] {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if c, ok := p.counters[name]; ok {
+		return c
+	}
+	keys := labelKeys(labels)
+	vec := prometheus.NewCounterVec(prometheus.CounterOpts{Name: name, Help: p.help(name)}, keys)
+	c := &labelledVec[*prometheus.CounterVec]{vec: register(p, vec), keys: keys}
+	p.counters[name] = c
+	return c
+}
+
+func (p *PrometheusRecorder) histogram(name string, labels Labels) *labelledVec[*prometheus.HistogramVec] {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if h, ok := p.histograms[name]; ok {
+		return h
+	}
+	keys := labelKeys(labels)
+	vec := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name: name, Help: p.help(name), Buckets: p.buckets,
+	}, keys)
+	h := &labelledVec[*prometheus.HistogramVec]{vec: register(p, vec), keys: keys}
+	p.histograms[name] = h
+	return h
+}
+
+func (p *PrometheusRecorder) gauge(name string, labels Labels) *labelledVec[*prometheus.GaugeVec] {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if g, ok := p.gauges[name]; ok {
+		return g
+	}
+	keys := labelKeys(labels)
+	vec := prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: name, Help: p.help(name)}, keys)
+	g := &labelledVec[*prometheus.GaugeVec]{vec: register(p, vec), keys: keys}
+	p.gauges[name] = g
+	return g
+}
+
+func (p *PrometheusRecorder) help(name string) string {
+	if h := p.Help[name]; h != "" {
+		return h
+	}
+	return name
+}
+
+func (p *PrometheusRecorder) log() ports.Logger {
+	if p.Log == nil {
+		return logctx.Nop()
+	}
+	return p.Log
+}
+
+// register adds c to p's registerer, reusing a collector that is already
+// registered under the same description. On a conflicting definition the
+// error is logged and c is returned unregistered, so recording into it
+// does not fail the caller but is not exported either.
+func register[C prometheus.Collector](p *PrometheusRecorder, c C) C {
+	if err := p.reg.Register(c); err != nil {
+		var are prometheus.AlreadyRegisteredError
+		if errors.As(err, &are) {
+			if existing, ok := are.ExistingCollector.(C); ok {
+				return existing
+			}
+		}
+		p.log().Error("metrics: cannot register collector; its samples are not exported",
+			"error", err)
+	}
+	return c
+}
+
+func labelKeys(labels Labels) []string {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// labelValues orders labels by the vector's keys. A label set that
+// differs from the one the vector was created with is rejected, and
+// reported the first time it happens for the metric.
+func labelValues[V any](p *PrometheusRecorder, name string, v *labelledVec[V], labels Labels) ([]string, bool) {
+	vals := make([]string, len(v.keys))
+	ok := len(labels) == len(v.keys)
+	for i, k := range v.keys {
+		val, found := labels[k]
+		ok = ok && found
+		vals[i] = val
+	}
+	if !ok {
+		if v.warned.CompareAndSwap(false, true) {
+			p.log().Warn("metrics: label set differs from the metric's first use; sample dropped",
+				"metric", name, "want", v.keys, "got", labelKeys(labels))
+		}
+		return nil, false
+	}
+	return vals, true
 }
 
 // Handler wraps the next handler to record counters and duration.
@@ -127,6 +306,15 @@ func (w *respWriter) WriteHeader(code int) {
 	w.ResponseWriter.WriteHeader(code)
 }
 
+// Flush passes flushes through so streaming responses work behind the
+// middleware.
+func (w *respWriter) Flush() {
+	_ = http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (w *respWriter) Unwrap() http.ResponseWriter { return w.ResponseWriter }
+
 func itoa(n int) string {
 	if n == 0 {
 		return "0"
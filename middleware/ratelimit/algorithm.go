@@ -0,0 +1,186 @@
+package ratelimit
+
+import (
+	"math"
+	"time"
+)
+
+// Algorithm selects how MemoryStore enforces a Limit. The window based
+// algorithms allow Capacity requests per window of Capacity/RefillRate
+// seconds, so one Limit means roughly the same rate under each.
+type Algorithm int
+
+const (
+	// TokenBucket allows bursts up to Capacity, refilled continuously.
+	TokenBucket Algorithm = iota
+	// SlidingWindowLog keeps every request time in the window: exact,
+	// at the cost of Capacity timestamps per key.
+	SlidingWindowLog
+	// SlidingWindowCounter weights the previous fixed window's count by
+	// its overlap with the sliding window; two counters per key.
+	SlidingWindowCounter
+	// GCRA is a token bucket stored as one theoretical arrival time, the
+	// same algorithm PostgresStore uses.
+	GCRA
+)
+
+func (a Algorithm) String() string {
+	switch a {
+	case TokenBucket:
+		return "token_bucket"
+	case SlidingWindowLog:
+		return "sliding_window_log"
+	case SlidingWindowCounter:
+		return "sliding_window_counter"
+	case GCRA:
+		return "gcra"
+	}
+	return "unknown"
+}
+
+// state is the per-key state of one algorithm.
+type state interface {
+	take(limit Limit, now time.Time) Result
+}
+
+func (a Algorithm) newState(limit Limit, now time.Time) state {
+	switch a {
+	case SlidingWindowLog:
+		return &windowLog{}
+	case SlidingWindowCounter:
+		return &windowCounter{start: now}
+	case GCRA:
+		return &gcra{tat: now}
+	}
+	return &bucket{tokens: limit.Capacity, lastSeen: now}
+}
+
+func window(limit Limit) time.Duration {
+	return max(seconds(limit.Capacity/limit.RefillRate), time.Nanosecond)
+}
+
+type bucket struct {
+	tokens   float64
+	lastSeen time.Time
+}
+
+func (b *bucket) take(limit Limit, now time.Time) Result {
+	elapsed := now.Sub(b.lastSeen).Seconds()
+	if elapsed > 0 {
+		b.tokens += elapsed * limit.RefillRate
+	}
+	if b.tokens > limit.Capacity {
+		b.tokens = limit.Capacity
+	}
+	b.lastSeen = now
+
+	// Tolerate float drift so exact refills are not rejected.
+	res := Result{Allowed: b.tokens >= 1-1e-9}
+	if res.Allowed {
+		b.tokens--
+	} else {
+		res.RetryAfter = seconds((1 - b.tokens) / limit.RefillRate)
+	}
+	res.Remaining = max(int(math.Floor(b.tokens+1e-9)), 0)
+	res.Reset = seconds((limit.Capacity - b.tokens) / limit.RefillRate)
+	return res
+}
+
+type gcra struct {
+	tat time.Time
+}
+
+func (g *gcra) take(limit Limit, now time.Time) Result {
+	interval := seconds(1 / limit.RefillRate)
+	burst := window(limit)
+	tat := g.tat
+	if tat.Before(now) {
+		tat = now
+	}
+	next := tat.Add(interval)
+	if next.Sub(now) > burst {
+		return Result{
+			RetryAfter: next.Sub(now) - burst,
+			Reset:      tat.Sub(now),
+		}
+	}
+	g.tat = next
+	ahead := next.Sub(now)
+	return Result{
+		Allowed:   true,
+		Remaining: int((burst - ahead) / interval),
+		Reset:     ahead,
+	}
+}
+
+type windowLog struct {
+	times []time.Time // oldest first
+}
+
+func (l *windowLog) take(limit Limit, now time.Time) Result {
+	w := window(limit)
+	cut := 0
+	for cut < len(l.times) && now.Sub(l.times[cut]) >= w {
+		cut++
+	}
+	l.times = l.times[cut:]
+	capacity := max(int(limit.Capacity), 1)
+	if len(l.times) >= capacity {
+		return Result{
+			RetryAfter: l.times[0].Add(w).Sub(now),
+			Reset:      l.times[len(l.times)-1].Add(w).Sub(now),
+		}
+	}
+	l.times = append(l.times, now)
+	return Result{
+		Allowed:   true,
+		Remaining: capacity - len(l.times),
+		Reset:     w,
+	}
+}
+
+type windowCounter struct {
+	start      time.Time // of the current fixed window
+	prev, curr float64
+}
+
+func (c *windowCounter) take(limit Limit, now time.Time) Result {
+	w := window(limit)
+	if elapsed := now.Sub(c.start); elapsed >= w {
+		n := elapsed / w
+		c.start = c.start.Add(n * w)
+		if n == 1 {
+			c.prev = c.curr
+		} else {
+			c.prev = 0
+		}
+		c.curr = 0
+	}
+	frac := float64(now.Sub(c.start)) / float64(w)
+	weight := 1 - frac
+	used := c.prev*weight + c.curr
+	untilNext := c.start.Add(w).Sub(now)
+
+	if used+1 > limit.Capacity {
+		res := Result{Reset: untilNext + w}
+		if c.curr+1 > limit.Capacity || c.prev == 0 {
+			res.RetryAfter = untilNext
+		} else {
+			// Wait until the previous window's share has decayed enough.
+			need := (used + 1 - limit.Capacity) / c.prev
+			res.RetryAfter = time.Duration(need * float64(w))
+		}
+		return res
+	}
+	c.curr++
+	used++
+	reset := untilNext
+	if c.prev > 0 {
+		reset += w
+	}
+	return Result{
+		Allowed:   true,
+		Remaining: int(math.Floor(limit.Capacity - used)),
+		Reset:     reset,
+	}
+}
@@ -0,0 +1,75 @@
+package ratelimit
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"strings"
+
+	"github.com/aatuh/api-toolkit/realip"
+)
+
+type KeyFn func(*http.Request) string
+
+type principalKey struct{}
+
+// WithPrincipal records the authenticated principal (user, client or
+// tenant ID) for ByPrincipal. Authentication middleware calls it once the
+// caller is verified.
+func WithPrincipal(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, principalKey{}, id)
+}
+
+// PrincipalFromContext returns the principal set by WithPrincipal.
+func PrincipalFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(principalKey{}).(string)
+	return id
+}
+
+// ByPrincipal keys by the principal set with WithPrincipal, or returns
+// "" for anonymous requests.
+func ByPrincipal(r *http.Request) string {
+	if id := PrincipalFromContext(r.Context()); id != "" {
+		return "principal:" + id
+	}
+	return ""
+}
+
+// ByIP keys by the client IP resolved by the realip middleware, or the
+// peer address without it.
+func ByIP(r *http.Request) string {
+	return "ip:" + realip.FromRequest(r)
+}
+
+// ByHeader keys by a hash of the named header, e.g. an API key, so
+// secrets are never held by the store. Only use it for headers that are
+// verified before the limiter runs; otherwise clients can rotate values
+// to escape their limit.
+func ByHeader(name string) KeyFn {
+	return func(r *http.Request) string {
+		v := r.Header.Get(name)
+		if v == "" {
+			return ""
+		}
+		sum := sha256.Sum256([]byte(v))
+		return "header:" + strings.ToLower(name) + ":" +
+			base64.RawURLEncoding.EncodeToString(sum[:16])
+	}
+}
+
+// FirstOf returns the first non-empty key.
+func FirstOf(fns ...KeyFn) KeyFn {
+	return func(r *http.Request) string {
+		for _, fn := range fns {
+			if k := fn(r); k != "" {
+				return k
+			}
+		}
+		return ""
+	}
+}
+
+// DefaultKey keys authenticated requests by principal and anonymous ones
+// by client IP.
+var DefaultKey = FirstOf(ByPrincipal, ByIP)
@@ -0,0 +1,139 @@
+package ratelimit
+
+import (
+	"container/list"
+	"context"
+	"hash/maphash"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/aatuh/api-toolkit/middleware/metrics"
+)
+
+// MemoryOptions configures MemoryStore.
+type MemoryOptions struct {
+	Algorithm Algorithm
+	// Shards splits keys across independently locked maps. Defaults to 32.
+	Shards int
+	// IdleTTL evicts keys not seen for this long. It should exceed the
+	// longest window in use, or evicted keys regain their full limit
+	// early. Defaults to 10 minutes.
+	IdleTTL time.Duration
+	// MaxKeys caps the number of keys; the least recently used key of a
+	// full shard is evicted. Zero means no cap beyond IdleTTL.
+	MaxKeys int
+	// Metrics receives the ratelimit_buckets gauge when it implements
+	// metrics.GaugeRecorder.
+	Metrics metrics.MetricsRecorder
+	// Name labels the gauge as store=<Name>. Defaults to "memory".
+	Name string
+}
+
+// MemoryStore keeps limiter state in process memory. Each replica
+// enforces its own limit; use PostgresStore to share one across replicas.
+// Keys are sharded to reduce lock contention, and idle keys are swept
+// lazily as their shard is used, so no background goroutine is needed.
+type MemoryStore struct {
+	opts     MemoryOptions
+	seed     maphash.Seed
+	shards   []*shard
+	perShard int
+	size     atomic.Int64
+}
+
+type shard struct {
+	mu        sync.Mutex
+	items     map[string]*list.Element
+	lru       list.List // of *entry, most recently used first
+	lastSweep time.Time
+}
+
+type entry struct {
+	key      string
+	st       state
+	lastSeen time.Time
+}
+
+// NewMemoryStore returns an empty in-memory store.
+func NewMemoryStore(opts MemoryOptions) *MemoryStore {
+	if opts.Shards <= 0 {
+		opts.Shards = 32
+	}
+	if opts.IdleTTL <= 0 {
+		opts.IdleTTL = 10 * time.Minute
+	}
+	if opts.Metrics == nil {
+		opts.Metrics = metrics.NoopMetrics{}
+	}
+	if opts.Name == "" {
+		opts.Name = "memory"
+	}
+	s := &MemoryStore{
+		opts:   opts,
+		seed:   maphash.MakeSeed(),
+		shards: make([]*shard, opts.Shards),
+	}
+	if opts.MaxKeys > 0 {
+		s.perShard = max((opts.MaxKeys+opts.Shards-1)/opts.Shards, 1)
+	}
+	for i := range s.shards {
+		s.shards[i] = &shard{items: make(map[string]*list.Element)}
+	}
+	return s
+}
+
+// Len returns the number of keys currently held.
+func (s *MemoryStore) Len() int { return int(s.size.Load()) }
+
+func (s *MemoryStore) Take(_ context.Context, key string, limit Limit, now time.Time) (Result, error) {
+	sh := s.shards[maphash.String(s.seed, key)%uint64(len(s.shards))]
+	sh.mu.Lock()
+	delta := s.sweep(sh, now)
+	el := sh.items[key]
+	if el == nil {
+		if s.perShard > 0 && sh.lru.Len() >= s.perShard {
+			s.remove(sh, sh.lru.Back())
+			delta--
+		}
+		el = sh.lru.PushFront(&entry{key: key, st: s.opts.Algorithm.newState(limit, now)})
+		sh.items[key] = el
+		delta++
+	} else {
+		sh.lru.MoveToFront(el)
+	}
+	e := el.Value.(*entry)
+	e.lastSeen = now
+	res := e.st.take(limit, now)
+	sh.mu.Unlock()
+
+	if delta != 0 {
+		n := s.size.Add(int64(delta))
+		metrics.SetGauge(s.opts.Metrics, "ratelimit_buckets", float64(n),
+			metrics.Labels{"store": s.opts.Name})
+	}
+	return res, nil
+}
+
+// sweep drops idle keys from the back of the LRU list, at most once per
+// quarter IdleTTL per shard. It returns the negative number removed.
+func (s *MemoryStore) sweep(sh *shard, now time.Time) int {
+	if now.Sub(sh.lastSweep) < s.opts.IdleTTL/4 {
+		return 0
+	}
+	sh.lastSweep = now
+	removed := 0
+	for el := sh.lru.Back(); el != nil; el = sh.lru.Back() {
+		if now.Sub(el.Value.(*entry).lastSeen) < s.opts.IdleTTL {
+			break
+		}
+		s.remove(sh, el)
+		removed--
+	}
+	return removed
+}
+
+func (s *MemoryStore) remove(sh *shard, el *list.Element) {
+	delete(sh.items, el.Value.(*entry).key)
+	sh.lru.Remove(el)
+}
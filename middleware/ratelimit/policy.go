@@ -0,0 +1,74 @@
+package ratelimit
+
+import (
+	"net/http"
+	"sync"
+)
+
+// Policy is a named limit, such as a strict one for login and a generous
+// one for reads.
+type Policy struct {
+	Name       string
+	Capacity   float64
+	RefillRate float64
+	// Key defaults to the Policies' shared Key.
+	Key KeyFn
+}
+
+// Policies holds named policies sharing one Store, Clock, Metrics and
+// Key. Each policy counts separately, since keys are namespaced by
+// policy name. Attach a policy to a route group with Handler:
+//
+//	auth := chi.New()
+//	auth.Use(limits.Handler("login"))
+//	r.Mount("/auth", auth)
+//
+// A route under several policies is limited by each.
+type Policies struct {
+	base Options
+	mu   sync.RWMutex
+	m    map[string]*Middleware
+}
+
+// NewPolicies returns a policy set. base supplies the shared settings,
+// defaulted as by New; its Capacity, RefillRate and Name are ignored.
+func NewPolicies(base Options, policies ...Policy) *Policies {
+	p := &Policies{base: New(base).opts, m: make(map[string]*Middleware)}
+	for _, pol := range policies {
+		p.Add(pol)
+	}
+	return p
+}
+
+// Add registers or replaces a policy.
+func (p *Policies) Add(pol Policy) {
+	opts := p.base
+	opts.Name = pol.Name
+	opts.Capacity = pol.Capacity
+	opts.RefillRate = pol.RefillRate
+	if pol.Key != nil {
+		opts.Key = pol.Key
+	}
+	mw := New(opts)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.m[pol.Name] = mw
+}
+
+// Lookup returns the middleware for a policy.
+func (p *Policies) Lookup(name string) (*Middleware, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	mw, ok := p.m[name]
+	return mw, ok
+}
+
+// Handler returns the middleware for a policy. It panics if the policy
+// is unknown, so typos surface while wiring routes.
+func (p *Policies) Handler(name string) func(http.Handler) http.Handler {
+	mw, ok := p.Lookup(name)
+	if !ok {
+		panic("ratelimit: unknown policy " + name)
+	}
+	return mw.Handler
+}
@@ -0,0 +1,169 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/aatuh/api-toolkit/logctx"
+	"github.com/aatuh/api-toolkit/ports"
+)
+
+// PostgresOptions configures PostgresStore.
+type PostgresOptions struct {
+	Pool ports.DatabasePool
+	// Table holds one row per key. Defaults to "ratelimit_buckets" and is
+	// created if missing.
+	Table string
+	// FailOpen allows requests when the database cannot be reached. By
+	// default they are rejected with ErrUnavailable.
+	FailOpen bool
+	// Timeout bounds each database round trip. Defaults to 100ms.
+	Timeout time.Duration
+	Log     ports.Logger
+}
+
+// PostgresStore shares limits across replicas. It implements the token
+// bucket as GCRA: each key stores a theoretical arrival time, updated
+// atomically in a single statement. Time is read from the database, so
+// replicas need not agree on wall time.
+type PostgresStore struct {
+	opts  PostgresOptions
+	table string
+	take  string
+	// failing is set while the database is unreachable, so an outage is
+	// logged once rather than on every request.
+	failing atomic.Bool
+}
+
+// NewPostgresStore creates the bucket table if needed.
+func NewPostgresStore(ctx context.Context, opts PostgresOptions) (*PostgresStore, error) {
+	if opts.Pool == nil {
+		return nil, errors.New("ratelimit: pool is required")
+	}
+	if opts.Table == "" {
+		opts.Table = "ratelimit_buckets"
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 100 * time.Millisecond
+	}
+	s := &PostgresStore{opts: opts, table: quoteIdent(opts.Table)}
+	// $2 is the emission interval (seconds per token) and $3 the burst
+	// window (interval times capacity). A key is allowed when its new
+	// arrival time stays within the window; otherwise the update's WHERE
+	// fails and the second branch reports the current arrival time.
+	s.take = fmt.Sprintf(`
+WITH taken AS (
+  INSERT INTO %[1]s AS b (key, tat)
+  VALUES ($1, now() + $2::float8 * interval '1 second')
+  ON CONFLICT (key) DO UPDATE
+  SET tat = GREATEST(b.tat, now()) + $2::float8 * interval '1 second'
+  WHERE GREATEST(b.tat, now()) + $2::float8 * interval '1 second'
+        <= now() + $3::float8 * interval '1 second'
+  RETURNING b.tat
+)
+SELECT true, EXTRACT(EPOCH FROM (tat - now()))::float8 FROM taken
+UNION ALL
+SELECT false, EXTRACT(EPOCH FROM (GREATEST(tat, now()) - now()))::float8
+FROM %[1]s WHERE key = $1 AND NOT EXISTS (SELECT 1 FROM taken);`, s.table)
+	if err := s.exec(ctx, fmt.Sprintf(`
+CREATE TABLE IF NOT EXISTS %s (
+  key TEXT PRIMARY KEY,
+  tat TIMESTAMPTZ NOT NULL
+);`, s.table)); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *PostgresStore) Take(ctx context.Context, key string, limit Limit, _ time.Time) (Result, error) {
+	interval := 1 / limit.RefillRate
+	window := interval * limit.Capacity
+	allowed, ahead, err := s.query(ctx, key, interval, window)
+	if err != nil {
+		// A request cancelled by its client says nothing about the
+		// database.
+		if ctx.Err() == nil && s.failing.CompareAndSwap(false, true) {
+			s.log(ctx).Warn("rate limit store unavailable", "fail_open", s.opts.FailOpen, "error", err)
+		}
+		if s.opts.FailOpen {
+			return Result{Allowed: true, Remaining: int(limit.Capacity) - 1}, nil
+		}
+		return Result{}, fmt.Errorf("%w: %w", ErrUnavailable, err)
+	}
+	if s.failing.CompareAndSwap(true, false) {
+		s.log(ctx).Info("rate limit store recovered")
+	}
+	res := Result{Allowed: allowed, Reset: seconds(ahead)}
+	if allowed {
+		res.Remaining = int(math.Floor((window - ahead) / interval))
+	} else {
+		res.RetryAfter = seconds(ahead + interval - window)
+	}
+	return res, nil
+}
+
+func (s *PostgresStore) log(ctx context.Context) ports.Logger {
+	if s.opts.Log != nil {
+		return s.opts.Log
+	}
+	return logctx.FromContext(ctx)
+}
+
+// query runs the GCRA statement. A key inserted concurrently by another
+// transaction may be invisible to the second branch; that is reported as
+// a rejection one interval long.
+func (s *PostgresStore) query(ctx context.Context, key string, interval, window float64) (bool, float64, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.opts.Timeout)
+	defer cancel()
+	conn, err := s.opts.Pool.Acquire(ctx)
+	if err != nil {
+		return false, 0, err
+	}
+	defer conn.Release()
+	rows, err := conn.Query(ctx, s.take, key, interval, window)
+	if err != nil {
+		return false, 0, err
+	}
+	defer rows.Close()
+	allowed, ahead := false, window
+	if rows.Next() {
+		if err := rows.Scan(&allowed, &ahead); err != nil {
+			return false, 0, err
+		}
+	}
+	return allowed, ahead, rows.Err()
+}
+
+// Sweep deletes keys whose buckets have fully refilled; they behave
+// exactly like absent keys. Run it periodically to bound the table.
+func (s *PostgresStore) Sweep(ctx context.Context) (int64, error) {
+	conn, err := s.opts.Pool.Acquire(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Release()
+	res, err := conn.Exec(ctx, fmt.Sprintf(`DELETE FROM %s WHERE tat < now();`, s.table))
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected(), nil
+}
+
+func (s *PostgresStore) exec(ctx context.Context, sql string) error {
+	conn, err := s.opts.Pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+	_, err = conn.Exec(ctx, sql)
+	return err
+}
+
+func quoteIdent(ident string) string {
+	return `"` + strings.ReplaceAll(ident, `"`, `""`) + `"`
+}
@@ -1,31 +1,39 @@
 package ratelimit
 
 import (
-	"net"
 	"net/http"
-	"strings"
-	"sync"
+	"strconv"
 	"time"
-)
 
-type KeyFn func(*http.Request) string
+	"github.com/aatuh/api-toolkit/clock"
+	"github.com/aatuh/api-toolkit/httpx"
+	"github.com/aatuh/api-toolkit/middleware/metrics"
+	"github.com/aatuh/api-toolkit/ports"
+)
 
 type Options struct {
 	Capacity   float64 // tokens
 	RefillRate float64 // tokens per second
-	Key        KeyFn   // how to key buckets
+	Key        KeyFn   // how to key buckets; defaults to DefaultKey
+	// RetryAfter, when set, overrides the Retry-After computed from the
+	// store's result.
 	RetryAfter time.Duration
+	Clock      ports.Clock // defaults to the system clock
+	// Store holds the buckets. Defaults to a MemoryStore using
+	// Algorithm and named after Name, which limits each replica
+	// separately.
+	Store     Store
+	Algorithm Algorithm
+	// Metrics counts rejections as ratelimit_rejections_total and is
+	// passed to the default store.
+	Metrics metrics.MetricsRecorder
+	// Name namespaces keys in the store and labels metrics as
+	// policy=<Name>. Defaults to "default".
+	Name string
 }
 
 type Middleware struct {
 	opts Options
-	mu   sync.Mutex
-	m    map[string]*bucket
-}
-
-type bucket struct {
-	tokens   float64
-	lastSeen time.Time
 }
 
 func New(opts Options) *Middleware {
@@ -36,57 +44,81 @@ func New(opts Options) *Middleware {
 		opts.RefillRate = 10
 	}
 	if opts.Key == nil {
-		opts.Key = clientIP
+		opts.Key = DefaultKey
+	}
+	opts.Clock = clock.OrSystem(opts.Clock)
+	if opts.Metrics == nil {
+		opts.Metrics = metrics.NoopMetrics{}
+	}
+	if opts.Name == "" {
+		opts.Name = "default"
+	}
+	if opts.Store == nil {
+		opts.Store = NewMemoryStore(MemoryOptions{
+			Algorithm: opts.Algorithm,
+			Metrics:   opts.Metrics,
+			Name:      opts.Name,
+		})
 	}
-	return &Middleware{opts: opts, m: make(map[string]*bucket)}
+	return &Middleware{opts: opts}
 }
 
+// Handler limits requests per key. Every response carries RateLimit-Limit,
+// RateLimit-Remaining, RateLimit-Reset and RateLimit-Policy headers (IETF
+// draft-ietf-httpapi-ratelimit-headers); when several limiters apply, the
+// one closest to exhaustion wins. Rejections are 429 Problems with
+// Retry-After.
 func (m *Middleware) Handler(next http.Handler) http.Handler {
+	limit := Limit{Capacity: m.opts.Capacity, RefillRate: m.opts.RefillRate}
+	policy := itoa(int(limit.Capacity)) + ";w=" + itoa(ceilSeconds(window(limit))) +
+		";name=" + strconv.Quote(m.opts.Name)
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		key := m.opts.Key(r)
-		now := time.Now()
-
-		m.mu.Lock()
-		b := m.m[key]
-		if b == nil {
-			b = &bucket{tokens: m.opts.Capacity, lastSeen: now}
-			m.m[key] = b
+		if key == "" {
+			key = ByIP(r)
 		}
-		elapsed := now.Sub(b.lastSeen).Seconds()
-		b.tokens += elapsed * m.opts.RefillRate
-		if b.tokens > m.opts.Capacity {
-			b.tokens = m.opts.Capacity
+		res, err := m.opts.Store.Take(r.Context(), m.opts.Name+"|"+key, limit, m.opts.Clock.Now())
+		if err != nil {
+			httpx.WriteError(w, r, err)
+			return
 		}
-		b.lastSeen = now
-
-		if b.tokens < 1 {
-			m.mu.Unlock()
+		setHeaders(w.Header(), limit, res, policy)
+		if !res.Allowed {
+			m.opts.Metrics.IncCounter("ratelimit_rejections_total", metrics.Labels{"policy": m.opts.Name})
 			ra := m.opts.RetryAfter
 			if ra <= 0 {
-				ra = time.Second
+				ra = res.RetryAfter
 			}
-			w.Header().Set("Retry-After", itoa(int(ra.Seconds())))
-			http.Error(w, "rate limit exceeded", http.StatusTooManyRequests)
+			w.Header().Set("Retry-After", itoa(ceilSeconds(ra)))
+			httpx.WriteProblemFor(w, r, http.StatusTooManyRequests, httpx.Problem{
+				Title:    http.StatusText(http.StatusTooManyRequests),
+				Status:   http.StatusTooManyRequests,
+				Detail:   "rate limit exceeded",
+				DetailID: "problem.rate_limited",
+			})
 			return
 		}
-		b.tokens -= 1
-		m.mu.Unlock()
-
 		next.ServeHTTP(w, r)
 	})
 }
 
-func clientIP(r *http.Request) string {
-	xff := r.Header.Get("X-Forwarded-For")
-	if xff != "" {
-		parts := strings.Split(xff, ",")
-		return strings.TrimSpace(parts[0])
+func setHeaders(h http.Header, limit Limit, res Result, policy string) {
+	if prev, err := strconv.Atoi(h.Get("RateLimit-Remaining")); err == nil && prev <= res.Remaining {
+		return
 	}
-	host, _, err := net.SplitHostPort(r.RemoteAddr)
-	if err != nil {
-		return r.RemoteAddr
+	h.Set("RateLimit-Limit", itoa(int(limit.Capacity)))
+	h.Set("RateLimit-Remaining", itoa(res.Remaining))
+	h.Set("RateLimit-Reset", itoa(int((res.Reset+time.Second-1)/time.Second)))
+	h.Set("RateLimit-Policy", policy)
+}
+
+// ceilSeconds rounds d up to whole seconds, at least one.
+func ceilSeconds(d time.Duration) int {
+	s := int((d + time.Second - 1) / time.Second)
+	if s < 1 {
+		s = 1
 	}
-	return host
+	return s
 }
 
 func itoa(n int) string {
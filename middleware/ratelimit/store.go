@@ -0,0 +1,61 @@
+package ratelimit
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/aatuh/api-toolkit/httpx"
+)
+
+// Limit is a token bucket: up to Capacity requests at once, refilled at
+// RefillRate tokens per second.
+type Limit struct {
+	Capacity   float64
+	RefillRate float64
+}
+
+// Result is the outcome of taking one token.
+type Result struct {
+	Allowed bool
+	// Remaining is the number of requests still allowed right now.
+	Remaining int
+	// RetryAfter is how long until a rejected request would be allowed.
+	// It is zero when Allowed.
+	RetryAfter time.Duration
+	// Reset is how long until the bucket is full again.
+	Reset time.Duration
+}
+
+// Store keeps rate limit state. Take consumes one token for key if one
+// is available. now comes from the middleware's clock; stores with their
+// own clock, such as PostgresStore, may ignore it.
+type Store interface {
+	Take(ctx context.Context, key string, limit Limit, now time.Time) (Result, error)
+}
+
+// ErrUnavailable is returned by stores that cannot reach their backend
+// and are configured to fail closed. It renders as 503 through
+// httpx.WriteError.
+var ErrUnavailable error = &unavailableError{}
+
+type unavailableError struct{}
+
+func (*unavailableError) Error() string { return "ratelimit: store unavailable" }
+
+// Problem implements httpx.ProblemError.
+func (*unavailableError) Problem() httpx.Problem {
+	return httpx.Problem{
+		Title:    http.StatusText(http.StatusServiceUnavailable),
+		Status:   http.StatusServiceUnavailable,
+		Detail:   "rate limiting is temporarily unavailable",
+		DetailID: "problem.rate_limit_unavailable",
+	}
+}
+
+func seconds(s float64) time.Duration {
+	if s <= 0 {
+		return 0
+	}
+	return time.Duration(s * float64(time.Second))
+}
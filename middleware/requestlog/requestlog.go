@@ -1,39 +1,60 @@
 package requestlog
 
 import (
-	"net"
 	"net/http"
-	"strings"
 	"time"
 
+	"github.com/aatuh/api-toolkit/chi"
+	"github.com/aatuh/api-toolkit/logctx"
+	"github.com/aatuh/api-toolkit/middleware/trace"
 	"github.com/aatuh/api-toolkit/ports"
+	"github.com/aatuh/api-toolkit/realip"
+	"github.com/aatuh/api-toolkit/redact"
 )
 
 type Middleware struct {
 	Log ports.Logger
 }
 
-func New(log ports.Logger) *Middleware { return &Middleware{Log: log} }
+// New wraps log with the default redacting decorator, so secrets in the
+// access log and in handler logs taken from the context are masked. Set
+// Log directly to opt out.
+func New(log ports.Logger) *Middleware { return &Middleware{Log: redact.Wrap(log)} }
 
+// Handler logs one line per request and stores a child logger carrying
+// the request correlation fields in the request context, retrievable via
+// logctx.FromContext.
 func (m *Middleware) Handler(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
+		log := m.Log.With(correlation(r)...)
+		r = r.WithContext(logctx.IntoContext(r.Context(), log))
+
 		ww := &respWriter{ResponseWriter: w, status: 200}
 		next.ServeHTTP(ww, r)
 
-		m.Log.Info("http",
+		log.Info("http",
 			"method", r.Method,
 			"path", r.URL.Path,
 			"status", ww.status,
 			"bytes", ww.bytes,
 			"dur_ms", time.Since(start).Milliseconds(),
-			"ip", clientIP(r),
+			"ip", realip.FromRequest(r),
 			"ua", r.UserAgent(),
-			"rid", requestID(r),
 		)
 	})
 }
 
+// correlation returns the request ID and, when the trace middleware ran
+// first, the trace and span IDs.
+func correlation(r *http.Request) []any {
+	kv := []any{"rid", requestID(r)}
+	if tid := trace.GetTraceID(r); tid != "" {
+		kv = append(kv, "trace_id", tid, "span_id", trace.GetSpanID(r))
+	}
+	return kv
+}
+
 type respWriter struct {
 	http.ResponseWriter
 	status int
@@ -51,22 +72,15 @@ func (w *respWriter) Write(b []byte) (int, error) {
 	return n, err
 }
 
-func clientIP(r *http.Request) string {
-	xff := r.Header.Get("X-Forwarded-For")
-	if xff != "" {
-		parts := strings.Split(xff, ",")
-		return strings.TrimSpace(parts[0])
-	}
-	host, _, err := net.SplitHostPort(r.RemoteAddr)
-	if err != nil {
-		return r.RemoteAddr
-	}
-	return host
+// Flush passes flushes through so streaming responses work behind the
+// middleware.
+func (w *respWriter) Flush() {
+	_ = http.NewResponseController(w.ResponseWriter).Flush()
 }
 
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (w *respWriter) Unwrap() http.ResponseWriter { return w.ResponseWriter }
+
 func requestID(r *http.Request) string {
-	if v := r.Header.Get("X-Request-ID"); v != "" {
-		return v
-	}
-	return ""
+	return chi.GetRequestID(r)
 }
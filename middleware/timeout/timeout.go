@@ -1,16 +1,226 @@
 package timeout
 
 import (
+	"context"
+	"errors"
 	"net/http"
+	"strings"
+	"sync"
 	"time"
+
+	"github.com/aatuh/api-toolkit/httpx"
 )
 
 type Middleware struct {
 	Timeout time.Duration
+	// Exempt selects requests served without a timeout, such as
+	// long-lived streams. Match on the route rather than on request
+	// headers, which the client controls.
+	Exempt func(r *http.Request) bool
+	// Budget overrides Timeout per request: zero keeps Timeout and a
+	// negative value disables the timeout. See Routes.
+	Budget func(r *http.Request) time.Duration
+	// Status is sent when the deadline passes before the response has
+	// started: http.StatusGatewayTimeout (default) or
+	// http.StatusServiceUnavailable.
+	Status int
 }
 
-func New(d time.Duration) *Middleware { return &Middleware{Timeout: d} }
+func New(d time.Duration) *Middleware {
+	return &Middleware{Timeout: d}
+}
 
+// Handler runs next with a context deadline. Handlers should honor
+// r.Context(); txpostgres.Manager also turns the deadline into a
+// statement_timeout. When the deadline passes before the response has
+// started, the client gets a timeout Problem and later writes fail with
+// http.ErrHandlerTimeout. When a streamed response has already started,
+// the connection is aborted so the client sees a truncated response
+// rather than a complete-looking one. Unlike http.TimeoutHandler,
+// responses are not buffered and Flush works.
 func (m *Middleware) Handler(next http.Handler) http.Handler {
-	return http.TimeoutHandler(next, m.Timeout, "request timeout")
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if m.Exempt != nil && m.Exempt(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		d := m.Timeout
+		if m.Budget != nil {
+			if b := m.Budget(r); b != 0 {
+				d = b
+			}
+		}
+		if d <= 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), d)
+		defer cancel()
+		r = r.WithContext(ctx)
+
+		tw := &timeoutWriter{w: w, h: make(http.Header)}
+		done := make(chan struct{})
+		panicked := make(chan any, 1)
+		go func() {
+			defer func() {
+				if p := recover(); p != nil {
+					panicked <- p
+				}
+			}()
+			next.ServeHTTP(tw, r)
+			close(done)
+		}()
+
+		select {
+		case p := <-panicked:
+			panic(p)
+		case <-done:
+			tw.finish()
+			return
+		case <-ctx.Done():
+		}
+		select {
+		case <-done:
+			// Finished just as the deadline passed.
+			tw.finish()
+			return
+		default:
+		}
+		if started := tw.timeout(); started {
+			panic(http.ErrAbortHandler)
+		}
+		if !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			// The client went away; nobody reads the response.
+			return
+		}
+		status := m.Status
+		if status == 0 {
+			status = http.StatusGatewayTimeout
+		}
+		httpx.WriteProblemFor(w, r, status, httpx.Problem{
+			Title:    http.StatusText(status),
+			Status:   status,
+			Detail:   "request timed out",
+			DetailID: "problem.timeout",
+		})
+	})
+}
+
+// Remaining returns the time left before the request deadline, if any.
+func Remaining(ctx context.Context) (time.Duration, bool) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return 0, false
+	}
+	return time.Until(deadline), true
+}
+
+// Routes returns a Budget func picking the longest matching path prefix.
+// Use a negative duration to exempt a prefix.
+func Routes(budgets map[string]time.Duration) func(r *http.Request) time.Duration {
+	return func(r *http.Request) time.Duration {
+		best, d := -1, time.Duration(0)
+		for prefix, b := range budgets {
+			if strings.HasPrefix(r.URL.Path, prefix) && len(prefix) > best {
+				best, d = len(prefix), b
+			}
+		}
+		return d
+	}
+}
+
+// timeoutWriter gives the handler its own header map and serializes its
+// writes with the timeout response. It deliberately has no Unwrap, so
+// handlers cannot reach the connection behind the guard.
+type timeoutWriter struct {
+	w http.ResponseWriter
+	h http.Header
+
+	mu          sync.Mutex
+	wroteHeader bool
+	timedOut    bool
+}
+
+// Header always returns the private map: the handler goroutine may
+// outlive a timeout, after which the server owns the real one. Trailers
+// set on it while streaming are copied over by finish.
+func (tw *timeoutWriter) Header() http.Header { return tw.h }
+
+func (tw *timeoutWriter) WriteHeader(code int) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut || tw.wroteHeader {
+		return
+	}
+	tw.writeHeaderLocked(code)
+}
+
+func (tw *timeoutWriter) Write(b []byte) (int, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	if !tw.wroteHeader {
+		tw.writeHeaderLocked(http.StatusOK)
+	}
+	return tw.w.Write(b)
+}
+
+func (tw *timeoutWriter) Flush() {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut {
+		return
+	}
+	if !tw.wroteHeader {
+		tw.writeHeaderLocked(http.StatusOK)
+	}
+	_ = http.NewResponseController(tw.w).Flush()
+}
+
+func (tw *timeoutWriter) writeHeaderLocked(code int) {
+	dst := tw.w.Header()
+	for k, v := range tw.h {
+		dst[k] = v
+	}
+	if code >= 200 {
+		tw.wroteHeader = true
+	}
+	tw.w.WriteHeader(code)
+}
+
+// finish commits headers for a handler that returned without writing,
+// or the trailers it set after the response started.
+func (tw *timeoutWriter) finish() {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut {
+		return
+	}
+	if !tw.wroteHeader {
+		tw.writeHeaderLocked(http.StatusOK)
+		return
+	}
+	dst := tw.w.Header()
+	declared := make(map[string]bool)
+	for _, v := range tw.h.Values("Trailer") {
+		for _, k := range strings.Split(v, ",") {
+			declared[http.CanonicalHeaderKey(strings.TrimSpace(k))] = true
+		}
+	}
+	for k, v := range tw.h {
+		if declared[k] || strings.HasPrefix(k, http.TrailerPrefix) {
+			dst[k] = v
+		}
+	}
+}
+
+// timeout stops further handler writes and reports whether the response
+// had already started.
+func (tw *timeoutWriter) timeout() bool {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	tw.timedOut = true
+	return tw.wroteHeader
 }
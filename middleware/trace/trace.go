@@ -6,6 +6,8 @@ import (
 	"encoding/hex"
 	"net/http"
 	"strings"
+
+	"github.com/aatuh/api-toolkit/logctx"
 )
 
 // W3C Trace Context (traceparent) format:
@@ -21,6 +23,7 @@ type ctxKey string
 const (
 	ctxTraceID ctxKey = "trace.trace_id"
 	ctxSpanID  ctxKey = "trace.span_id"
+	ctxFlags   ctxKey = "trace.flags"
 )
 
 // Options controls middleware behaviour.
@@ -55,8 +58,11 @@ func Middleware(opts Options) func(http.Handler) http.Handler {
 			// Always create a new span id for this server span.
 			spanID := newSpanID()
 
-			// Put into context
-			r = r.WithContext(withTrace(r.Context(), traceID, spanID))
+			// Put into context, enriching any request-scoped logger
+			ctx := withTrace(r.Context(), traceID, spanID)
+			ctx = context.WithValue(ctx, ctxFlags, opts.SampledFlag)
+			ctx = logctx.With(ctx, "trace_id", traceID, "span_id", spanID)
+			r = r.WithContext(ctx)
 
 			// Best-effort echo of traceparent for clients and downstreams
 			w.Header().Set(headerTraceParent, formatTraceParent(traceID, spanID, opts.SampledFlag))
@@ -68,16 +74,40 @@ func Middleware(opts Options) func(http.Handler) http.Handler {
 
 // GetTraceID returns the hex-encoded 16-byte trace id if present.
 func GetTraceID(r *http.Request) string {
-	v, _ := r.Context().Value(ctxTraceID).(string)
-	return v
+	return TraceIDFromContext(r.Context())
 }
 
 // GetSpanID returns the hex-encoded 8-byte span id if present.
 func GetSpanID(r *http.Request) string {
-	v, _ := r.Context().Value(ctxSpanID).(string)
+	return SpanIDFromContext(r.Context())
+}
+
+// TraceIDFromContext returns the trace id stored by Middleware, if any.
+func TraceIDFromContext(ctx context.Context) string {
+	v, _ := ctx.Value(ctxTraceID).(string)
+	return v
+}
+
+// SpanIDFromContext returns the server span id stored by Middleware, if any.
+func SpanIDFromContext(ctx context.Context) string {
+	v, _ := ctx.Value(ctxSpanID).(string)
 	return v
 }
 
+// TraceParentFromContext formats a traceparent header for outgoing calls,
+// with the current span as parent. It returns "" outside a traced request.
+func TraceParentFromContext(ctx context.Context) string {
+	traceID, spanID := TraceIDFromContext(ctx), SpanIDFromContext(ctx)
+	if traceID == "" || spanID == "" {
+		return ""
+	}
+	flags, ok := ctx.Value(ctxFlags).(byte)
+	if !ok {
+		flags = 0x01
+	}
+	return formatTraceParent(traceID, spanID, flags)
+}
+
 func withTrace(ctx context.Context, traceID, spanID string) context.Context {
 	ctx = context.WithValue(ctx, ctxTraceID, traceID)
 	ctx = context.WithValue(ctx, ctxSpanID, spanID)
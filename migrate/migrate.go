@@ -27,6 +27,7 @@ type Options struct {
 	AllowDangerousDown bool
 	EmbeddedFSs        []fs.FS // optional; multiple embedded FS
 	Log                ports.Logger
+	Clock              ports.Clock // optional; defaults to the system clock
 }
 
 // New builds an Adapter and pings the database.
@@ -51,6 +52,7 @@ func New(opts Options) (*Adapter, error) {
 		TableName:          opts.Table,
 		LockKey:            opts.LockKey,
 		AllowDangerousDown: opts.AllowDangerousDown,
+		Clock:              opts.Clock,
 		Logger: func(format string, args ...any) {
 			opts.Log.Info(fmt.Sprintf(format, args...))
 		},
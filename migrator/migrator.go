@@ -16,6 +16,9 @@ import (
 	"time"
 
 	_ "github.com/jackc/pgx/v5/stdlib"
+
+	"github.com/aatuh/api-toolkit/clock"
+	"github.com/aatuh/api-toolkit/ports"
 )
 
 // Runner executes SQL migrations transactionally and records state.
@@ -45,6 +48,9 @@ type Options struct {
 	AllowDangerousDown bool
 	// Logger outputs formatted status messages.
 	Logger func(format string, args ...any)
+	// Clock drives lock timeouts, execution timings and applied_at.
+	// Defaults to the system clock.
+	Clock ports.Clock
 }
 
 // Migration is a versioned SQL change.
@@ -74,6 +80,7 @@ func New(db *sql.DB, opts Options) *Runner {
 	if opts.LockKey == 0 {
 		opts.LockKey = defaultLock
 	}
+	opts.Clock = clock.OrSystem(opts.Clock)
 	return &Runner{DB: db, Opts: opts}
 }
 
@@ -286,9 +293,9 @@ func (r *Runner) applyOne(ctx context.Context, m *Migration) error {
 	if err != nil {
 		return err
 	}
-	start := time.Now()
+	start := r.Opts.Clock.Now()
 	_, err = tx.ExecContext(ctx, m.SQL)
-	execMS := int(time.Since(start).Milliseconds())
+	execMS := int(r.Opts.Clock.Since(start).Milliseconds())
 	if err != nil {
 		_ = tx.Rollback()
 		_ = r.record(ctx, m, execMS, false)
@@ -338,7 +345,7 @@ func (r *Runner) record(
 ) error {
 	q := fmt.Sprintf(`
 INSERT INTO %s (version, name, checksum, applied_at, exec_ms, success)
-VALUES ($1, $2, $3, NOW(), $4, $5)
+VALUES ($1, $2, $3, $4, $5, $6)
 ON CONFLICT (version) DO UPDATE SET
   name = EXCLUDED.name,
   checksum = EXCLUDED.checksum,
@@ -346,7 +353,7 @@ ON CONFLICT (version) DO UPDATE SET
   exec_ms = EXCLUDED.exec_ms,
   success = EXCLUDED.success;`, pq(r.Opts.TableName))
 	_, err := r.DB.ExecContext(
-		ctx, q, m.Version, m.Name, m.Checksum, execMS, ok,
+		ctx, q, m.Version, m.Name, m.Checksum, r.Opts.Clock.Now(), execMS, ok,
 	)
 	return err
 }
@@ -354,7 +361,7 @@ ON CONFLICT (version) DO UPDATE SET
 func (r *Runner) withLock(
 	ctx context.Context, fn func(context.Context) error,
 ) error {
-	ctx, cancel := context.WithTimeout(ctx, 10*time.Minute)
+	ctx, cancel := clock.WithTimeout(ctx, r.Opts.Clock, 10*time.Minute)
 	defer cancel()
 	if _, err := r.DB.ExecContext(
 		ctx, `SELECT pg_advisory_lock($1);`, r.Opts.LockKey,
@@ -12,11 +12,54 @@ type Logger interface {
 	Info(msg string, kv ...any)
 	Warn(msg string, kv ...any)
 	Error(msg string, kv ...any)
+	// With returns a child logger that adds kv to every entry.
+	With(kv ...any) Logger
+}
+
+// CallerSkipper is implemented by loggers that report the call site.
+// Decorators wrapping such a logger ask for WithCallerSkip(1) so entries
+// point at their caller rather than the decorator.
+type CallerSkipper interface {
+	WithCallerSkip(skip int) Logger
+}
+
+// LogLevelController reads and changes logger verbosity at runtime.
+type LogLevelController interface {
+	// Level returns the global level name, e.g. "info".
+	Level() string
+	// SetLevel changes the global level.
+	SetLevel(level string) error
+	// ValidateLevel reports whether SetLevel would accept level, without
+	// changing anything.
+	ValidateLevel(level string) error
+	// PackageLevels returns per-package overrides keyed by import path.
+	PackageLevels() map[string]string
+	// SetPackageLevel overrides the level for a package and its
+	// sub-packages; an empty level removes the override.
+	SetPackageLevel(pkg, level string) error
 }
 
 // Clock allows deterministic tests.
 type Clock interface {
 	Now() time.Time
+	Since(t time.Time) time.Duration
+	After(d time.Duration) <-chan time.Time
+	NewTimer(d time.Duration) Timer
+	NewTicker(d time.Duration) Ticker
+}
+
+// Timer mirrors *time.Timer so it can be driven by a Clock.
+type Timer interface {
+	C() <-chan time.Time
+	Stop() bool
+	Reset(d time.Duration) bool
+}
+
+// Ticker mirrors *time.Ticker so it can be driven by a Clock.
+type Ticker interface {
+	C() <-chan time.Time
+	Stop()
+	Reset(d time.Duration)
 }
 
 // IDGen generates unique IDs.
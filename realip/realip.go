@@ -0,0 +1,250 @@
+// Package realip resolves the client IP of requests that pass through
+// reverse proxies. Forwarding headers are only believed when they were
+// appended by a trusted proxy, so clients cannot spoof their address.
+package realip
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// Forwarding headers understood by Options.Header.
+const (
+	HeaderXForwardedFor = "X-Forwarded-For"
+	HeaderForwarded     = "Forwarded" // RFC 7239
+)
+
+// DefaultTrustedProxies are loopback and private ranges, where load
+// balancers and sidecars usually live.
+var DefaultTrustedProxies = []string{
+	"127.0.0.0/8",
+	"::1/128",
+	"10.0.0.0/8",
+	"172.16.0.0/12",
+	"192.168.0.0/16",
+	"fc00::/7",
+}
+
+// Options configures a Resolver.
+type Options struct {
+	// TrustedProxies are CIDRs or single IPs of proxies whose forwarding
+	// header is believed. Nil means DefaultTrustedProxies; an empty
+	// slice trusts no proxy, so only the peer address is used.
+	TrustedProxies []string
+	// Header is the forwarding header the proxies append to. Defaults to
+	// HeaderXForwardedFor. Only one is read: a header the proxies do not
+	// maintain would be entirely client-controlled.
+	Header string
+}
+
+// Resolver finds the client IP by walking the forwarding header from the
+// right, skipping trusted proxies; the first untrusted hop is the client.
+type Resolver struct {
+	trusted []netip.Prefix
+	header  string
+}
+
+// New parses opts.
+func New(opts Options) (*Resolver, error) {
+	cidrs := opts.TrustedProxies
+	if cidrs == nil {
+		cidrs = DefaultTrustedProxies
+	}
+	res := &Resolver{header: opts.Header}
+	if res.header == "" {
+		res.header = HeaderXForwardedFor
+	}
+	switch http.CanonicalHeaderKey(res.header) {
+	case HeaderXForwardedFor, HeaderForwarded:
+		res.header = http.CanonicalHeaderKey(res.header)
+	default:
+		return nil, fmt.Errorf("realip: unsupported header %q", opts.Header)
+	}
+	for _, c := range cidrs {
+		p, err := parsePrefix(c)
+		if err != nil {
+			return nil, fmt.Errorf("realip: trusted proxy %q: %w", c, err)
+		}
+		res.trusted = append(res.trusted, p)
+	}
+	return res, nil
+}
+
+// MustNew is New that panics on invalid options.
+func MustNew(opts Options) *Resolver {
+	r, err := New(opts)
+	if err != nil {
+		panic(err)
+	}
+	return r
+}
+
+var defaultResolver = MustNew(Options{})
+
+// Default returns the resolver trusting DefaultTrustedProxies through
+// X-Forwarded-For.
+func Default() *Resolver { return defaultResolver }
+
+func parsePrefix(s string) (netip.Prefix, error) {
+	s = strings.TrimSpace(s)
+	if strings.Contains(s, "/") {
+		p, err := netip.ParsePrefix(s)
+		if err != nil {
+			return netip.Prefix{}, err
+		}
+		return p.Masked(), nil
+	}
+	a, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	a = a.Unmap()
+	return netip.PrefixFrom(a, a.BitLen()), nil
+}
+
+// Trusted reports whether addr is a trusted proxy.
+func (res *Resolver) Trusted(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	for _, p := range res.trusted {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// ClientIP resolves the client address of r. When r did not come from a
+// trusted proxy, it is the peer address. If every hop is trusted, the
+// leftmost one is returned; a malformed hop stops the walk at the last
+// trusted address seen.
+func (res *Resolver) ClientIP(r *http.Request) netip.Addr {
+	peer, ok := peerAddr(r.RemoteAddr)
+	if !ok || !res.Trusted(peer) {
+		return peer
+	}
+	hops := res.hops(r.Header)
+	client := peer
+	for i := len(hops) - 1; i >= 0; i-- {
+		addr, err := parseHop(hops[i])
+		if err != nil {
+			break
+		}
+		client = addr
+		if !res.Trusted(addr) {
+			break
+		}
+	}
+	return client
+}
+
+func (res *Resolver) hops(h http.Header) []string {
+	var hops []string
+	for _, v := range h.Values(res.header) {
+		for _, elem := range splitList(v) {
+			if res.header == HeaderForwarded {
+				elem = forwardedFor(elem)
+			}
+			hops = append(hops, elem)
+		}
+	}
+	return hops
+}
+
+// Handler stores the resolved client IP in the request context and, like
+// chi's RealIP, rewrites r.RemoteAddr to it for code that reads the peer
+// address directly.
+func (res *Resolver) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := FromContext(r.Context()); !ok {
+			if ip := res.ClientIP(r); ip.IsValid() {
+				r = r.WithContext(context.WithValue(r.Context(), ctxKey{}, ip))
+				r.RemoteAddr = ip.String()
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+type ctxKey struct{}
+
+// FromContext returns the client IP stored by Resolver.Handler.
+func FromContext(ctx context.Context) (netip.Addr, bool) {
+	ip, ok := ctx.Value(ctxKey{}).(netip.Addr)
+	return ip, ok
+}
+
+// FromRequest returns the client IP resolved by Resolver.Handler, or the
+// peer address when the middleware did not run. Forwarding headers are
+// never read here, so the result cannot be spoofed.
+func FromRequest(r *http.Request) string {
+	if ip, ok := FromContext(r.Context()); ok {
+		return ip.String()
+	}
+	if ip, ok := peerAddr(r.RemoteAddr); ok {
+		return ip.String()
+	}
+	return r.RemoteAddr
+}
+
+func peerAddr(remote string) (netip.Addr, bool) {
+	host := remote
+	if h, _, err := net.SplitHostPort(remote); err == nil {
+		host = h
+	}
+	a, err := netip.ParseAddr(host)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return a.Unmap(), true
+}
+
+// parseHop parses an X-Forwarded-For entry or Forwarded "for" value:
+// a bare address, or a quoted or bracketed one with an optional port.
+func parseHop(s string) (netip.Addr, error) {
+	s = strings.Trim(strings.TrimSpace(s), `"`)
+	if ap, err := netip.ParseAddrPort(s); err == nil {
+		return ap.Addr().Unmap(), nil
+	}
+	s = strings.TrimSuffix(strings.TrimPrefix(s, "["), "]")
+	a, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Addr{}, err
+	}
+	return a.Unmap(), nil
+}
+
+// forwardedFor returns the "for" parameter of one Forwarded element, or
+// "" if it has none (which stops the walk).
+func forwardedFor(elem string) string {
+	for _, pair := range splitQuoted(elem, ';') {
+		k, v, ok := strings.Cut(pair, "=")
+		if ok && strings.EqualFold(strings.TrimSpace(k), "for") {
+			return strings.TrimSpace(v)
+		}
+	}
+	return ""
+}
+
+func splitList(v string) []string { return splitQuoted(v, ',') }
+
+// splitQuoted splits on sep outside double quotes.
+func splitQuoted(v string, sep byte) []string {
+	var parts []string
+	quoted, start := false, 0
+	for i := 0; i < len(v); i++ {
+		switch v[i] {
+		case '"':
+			quoted = !quoted
+		case sep:
+			if !quoted {
+				parts = append(parts, strings.TrimSpace(v[start:i]))
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, strings.TrimSpace(v[start:]))
+}
@@ -0,0 +1,49 @@
+package redact
+
+import "github.com/aatuh/api-toolkit/ports"
+
+// Logger is a ports.Logger decorator that masks secrets in key/value
+// pairs before they reach the wrapped logger.
+type Logger struct {
+	next ports.Logger
+	r    *Redactor
+}
+
+// NewLogger wraps next with a Redactor built from opts.
+func NewLogger(next ports.Logger, opts Options) ports.Logger {
+	return &Logger{next: skipCaller(next, 1), r: New(opts)}
+}
+
+// Wrap decorates next with the default Redactor. Loggers that are already
+// redacting are returned unchanged.
+func Wrap(next ports.Logger) ports.Logger {
+	if l, ok := next.(*Logger); ok {
+		return l
+	}
+	return &Logger{next: skipCaller(next, 1), r: Default()}
+}
+
+func (l *Logger) Debug(msg string, kv ...any) { l.next.Debug(msg, l.r.KV(kv)...) }
+func (l *Logger) Info(msg string, kv ...any)  { l.next.Info(msg, l.r.KV(kv)...) }
+func (l *Logger) Warn(msg string, kv ...any)  { l.next.Warn(msg, l.r.KV(kv)...) }
+func (l *Logger) Error(msg string, kv ...any) { l.next.Error(msg, l.r.KV(kv)...) }
+
+// With returns a child logger; kv is redacted once, up front.
+func (l *Logger) With(kv ...any) ports.Logger {
+	return &Logger{next: l.next.With(l.r.KV(kv)...), r: l.r}
+}
+
+// WithCallerSkip implements ports.CallerSkipper so further decorators
+// compose.
+func (l *Logger) WithCallerSkip(skip int) ports.Logger {
+	return &Logger{next: skipCaller(l.next, skip), r: l.r}
+}
+
+// skipCaller hides this decorator's frame from loggers that report the
+// call site.
+func skipCaller(next ports.Logger, skip int) ports.Logger {
+	if cs, ok := next.(ports.CallerSkipper); ok {
+		return cs.WithCallerSkip(skip)
+	}
+	return next
+}
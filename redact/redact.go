@@ -0,0 +1,342 @@
+package redact
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Mask replaces redacted values.
+const Mask = "***"
+
+// DefaultKeyPatterns match keys that look secret. Patterns are globs where
+// '*' matches any run of characters; keys are upper-cased and '-' is
+// treated as '_' before matching, so "X-Api-Key" matches "*_KEY".
+var DefaultKeyPatterns = []string{
+	"*SECRET*",
+	"*TOKEN*",
+	"*PASSWORD*",
+	"*PASSWD*",
+	"*_KEY",
+	"*APIKEY*",
+	"*AUTHORIZATION*",
+	"*COOKIE*",
+	"*CREDENTIAL*",
+}
+
+// Detector inspects a string value and returns its redacted form and
+// whether anything was redacted.
+type Detector func(s string) (string, bool)
+
+// RegexpDetector returns a Detector that replaces matches of re with repl,
+// which may reference capture groups as in regexp.ReplaceAllString.
+func RegexpDetector(re *regexp.Regexp, repl string) Detector {
+	return func(s string) (string, bool) {
+		if !re.MatchString(s) {
+			return s, false
+		}
+		return re.ReplaceAllString(s, repl), true
+	}
+}
+
+var (
+	bearerRe     = regexp.MustCompile(`(?i)\b(bearer|basic)\s+[A-Za-z0-9\-._~+/]+=*`)
+	jwtRe        = regexp.MustCompile(`\beyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*`)
+	dsnURLRe     = regexp.MustCompile(`([A-Za-z][A-Za-z0-9+.\-]*://[^:/?#\s@]*:)[^@/\s]+@`)
+	dsnKeywordRe = regexp.MustCompile(`(?i)\b(password|pwd)=[^\s;&]+`)
+)
+
+// DefaultDetectors returns detectors for bearer/basic credentials,
+// JWT-shaped strings and DSNs carrying a password.
+func DefaultDetectors() []Detector {
+	return []Detector{
+		RegexpDetector(bearerRe, "$1 "+Mask),
+		RegexpDetector(jwtRe, Mask),
+		RegexpDetector(dsnURLRe, "${1}"+Mask+"@"),
+		RegexpDetector(dsnKeywordRe, "$1="+Mask),
+	}
+}
+
+// Options configures a Redactor.
+type Options struct {
+	// KeyPatterns overrides DefaultKeyPatterns when non-nil.
+	KeyPatterns []string
+	// Detectors overrides DefaultDetectors when non-nil.
+	Detectors []Detector
+	// Mask overrides the replacement text. Defaults to Mask.
+	Mask string
+}
+
+// Redactor masks secrets in keys, string values and struct fields tagged
+// `redact:"true"`. It is safe for concurrent use.
+type Redactor struct {
+	patterns  []string
+	detectors []Detector
+	mask      string
+}
+
+// New creates a Redactor from opts.
+func New(opts Options) *Redactor {
+	r := &Redactor{
+		patterns:  DefaultKeyPatterns,
+		detectors: opts.Detectors,
+		mask:      opts.Mask,
+	}
+	if opts.KeyPatterns != nil {
+		r.patterns = make([]string, len(opts.KeyPatterns))
+		for i, p := range opts.KeyPatterns {
+			r.patterns[i] = normalizeKey(p)
+		}
+	}
+	if r.detectors == nil {
+		r.detectors = DefaultDetectors()
+	}
+	if r.mask == "" {
+		r.mask = Mask
+	}
+	return r
+}
+
+var (
+	defaultOnce     sync.Once
+	defaultRedactor *Redactor
+)
+
+// Default returns a shared Redactor using the default patterns and
+// detectors.
+func Default() *Redactor {
+	defaultOnce.Do(func() { defaultRedactor = New(Options{}) })
+	return defaultRedactor
+}
+
+// IsSecretKey reports whether key matches one of the key patterns.
+func (r *Redactor) IsSecretKey(key string) bool {
+	k := normalizeKey(key)
+	for _, p := range r.patterns {
+		if matchGlob(p, k) {
+			return true
+		}
+	}
+	return false
+}
+
+// String applies the value detectors to s.
+func (r *Redactor) String(s string) string {
+	for _, d := range r.detectors {
+		s, _ = d(s)
+	}
+	return s
+}
+
+// KV returns a copy of the key/value pairs with secrets masked. Values of
+// secret-looking keys are replaced entirely; other values are inspected.
+func (r *Redactor) KV(kv []any) []any {
+	if len(kv) == 0 {
+		return kv
+	}
+	out := make([]any, len(kv))
+	for i := 0; i < len(kv); i += 2 {
+		out[i] = kv[i]
+		if i+1 >= len(kv) {
+			out[i] = r.Value(kv[i])
+			break
+		}
+		if k, ok := kv[i].(string); ok && r.IsSecretKey(k) {
+			out[i+1] = r.mask
+			continue
+		}
+		out[i+1] = r.Value(kv[i+1])
+	}
+	return out
+}
+
+// Value returns v with secrets masked. Strings and errors go through the
+// detectors, maps with string keys are checked key by key, and structs
+// containing `redact:"true"` fields are converted into maps keyed by their
+// JSON names with those fields masked. Other values are returned as is.
+func (r *Redactor) Value(v any) any {
+	switch t := v.(type) {
+	case nil:
+		return nil
+	case string:
+		return r.String(t)
+	case error:
+		msg := t.Error()
+		if red := r.String(msg); red != msg {
+			return red
+		}
+		return t
+	}
+	return r.reflectValue(reflect.ValueOf(v), 0)
+}
+
+const maxDepth = 8
+
+func (r *Redactor) reflectValue(rv reflect.Value, depth int) any {
+	if !rv.IsValid() {
+		return nil
+	}
+	if depth > maxDepth {
+		return rv.Interface()
+	}
+	switch rv.Kind() {
+	case reflect.String:
+		if rv.Type() == stringType {
+			return r.String(rv.String())
+		}
+	case reflect.Map:
+		if rv.Type().Key().Kind() == reflect.String && !rv.IsNil() {
+			return r.mapValue(rv, depth)
+		}
+	case reflect.Interface:
+		if !rv.IsNil() {
+			return r.reflectValue(rv.Elem(), depth+1)
+		}
+	case reflect.Pointer:
+		if !rv.IsNil() && needsRedaction(rv.Type()) {
+			return r.reflectValue(rv.Elem(), depth+1)
+		}
+	case reflect.Struct:
+		if needsRedaction(rv.Type()) {
+			return r.structValue(rv, depth)
+		}
+	case reflect.Slice, reflect.Array:
+		if needsRedaction(rv.Type().Elem()) {
+			out := make([]any, rv.Len())
+			for i := range out {
+				out[i] = r.reflectValue(rv.Index(i), depth+1)
+			}
+			return out
+		}
+	}
+	if rv.CanInterface() {
+		return rv.Interface()
+	}
+	return nil
+}
+
+func (r *Redactor) mapValue(rv reflect.Value, depth int) any {
+	out := make(map[string]any, rv.Len())
+	iter := rv.MapRange()
+	for iter.Next() {
+		k := iter.Key().String()
+		if r.IsSecretKey(k) {
+			out[k] = r.mask
+			continue
+		}
+		out[k] = r.reflectValue(iter.Value(), depth+1)
+	}
+	return out
+}
+
+func (r *Redactor) structValue(rv reflect.Value, depth int) any {
+	t := rv.Type()
+	out := make(map[string]any, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name, skip := jsonName(f)
+		if skip {
+			continue
+		}
+		if f.Tag.Get("redact") == "true" {
+			out[name] = r.mask
+			continue
+		}
+		out[name] = r.reflectValue(rv.Field(i), depth+1)
+	}
+	return out
+}
+
+var (
+	stringType = reflect.TypeOf("")
+	tagCache   sync.Map // reflect.Type -> bool
+)
+
+// needsRedaction reports whether values of t can contain a field tagged
+// `redact:"true"`. Results are cached per type.
+func needsRedaction(t reflect.Type) bool {
+	if v, ok := tagCache.Load(t); ok {
+		return v.(bool)
+	}
+	res := scanType(t, make(map[reflect.Type]bool))
+	tagCache.Store(t, res)
+	return res
+}
+
+// scanType does the work for needsRedaction. Types already on the path in
+// visited are in progress and count as false, which terminates recursive
+// types. A false found below the top may therefore be partial, so only
+// true results are cached along the way.
+func scanType(t reflect.Type, visited map[reflect.Type]bool) bool {
+	if v, ok := tagCache.Load(t); ok {
+		return v.(bool)
+	}
+	if visited[t] {
+		return false
+	}
+	visited[t] = true
+	defer delete(visited, t)
+	res := false
+	switch t.Kind() {
+	case reflect.Pointer, reflect.Slice, reflect.Array:
+		res = scanType(t.Elem(), visited)
+	case reflect.Map:
+		res = t.Key().Kind() == reflect.String && scanType(t.Elem(), visited)
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if !f.IsExported() {
+				continue
+			}
+			if f.Tag.Get("redact") == "true" || scanType(f.Type, visited) {
+				res = true
+				break
+			}
+		}
+	}
+	if res {
+		tagCache.Store(t, true)
+	}
+	return res
+}
+
+func jsonName(f reflect.StructField) (string, bool) {
+	tag := f.Tag.Get("json")
+	if tag == "-" {
+		return "", true
+	}
+	if name, _, _ := strings.Cut(tag, ","); name != "" {
+		return name, false
+	}
+	return f.Name, false
+}
+
+func normalizeKey(k string) string {
+	return strings.ReplaceAll(strings.ToUpper(k), "-", "_")
+}
+
+// matchGlob matches s against a pattern where '*' matches any run of
+// characters, including none.
+func matchGlob(pattern, s string) bool {
+	parts := strings.Split(pattern, "*")
+	if len(parts) == 1 {
+		return pattern == s
+	}
+	if !strings.HasPrefix(s, parts[0]) {
+		return false
+	}
+	s = s[len(parts[0]):]
+	last := parts[len(parts)-1]
+	for _, p := range parts[1 : len(parts)-1] {
+		i := strings.Index(s, p)
+		if i < 0 {
+			return false
+		}
+		s = s[i+len(p):]
+	}
+	return strings.HasSuffix(s, last)
+}
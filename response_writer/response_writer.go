@@ -3,6 +3,9 @@ package response_writer
 import (
 	"encoding/json"
 	"net/http"
+
+	"github.com/aatuh/api-toolkit/codec"
+	"github.com/aatuh/api-toolkit/httpx"
 )
 
 func WriteJSON(w http.ResponseWriter, code int, v any) {
@@ -14,3 +17,24 @@ func WriteJSON(w http.ResponseWriter, code int, v any) {
 func WriteErr(w http.ResponseWriter, code int, msg string) {
 	WriteJSON(w, code, map[string]string{"error": msg})
 }
+
+// Write encodes v in the format negotiated from the request's Accept
+// header using codec.Default. When no registered format is acceptable it
+// writes a 406 Problem instead.
+func Write(w http.ResponseWriter, r *http.Request, code int, v any) {
+	WriteWith(codec.Default, w, r, code, v)
+}
+
+// WriteWith is Write with an explicit encoder registry.
+func WriteWith(reg *codec.Registry, w http.ResponseWriter, r *http.Request, code int, v any) {
+	enc, ok := reg.Negotiate(r)
+	if !ok {
+		httpx.WriteNotAcceptable(w, r, reg.MediaTypes())
+		return
+	}
+	if code == http.StatusNoContent {
+		w.WriteHeader(code)
+		return
+	}
+	httpx.WriteEncoded(w, r, code, enc, v)
+}
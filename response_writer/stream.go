@@ -0,0 +1,196 @@
+package response_writer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"iter"
+	"net/http"
+	"time"
+
+	"github.com/aatuh/api-toolkit/httpx"
+	"github.com/aatuh/api-toolkit/logctx"
+)
+
+// StreamErrorTrailer is the trailer set when a stream fails after the
+// response has started. Its value is the Problem detail.
+const StreamErrorTrailer = "Stream-Error"
+
+// StreamOptions configures WriteNDJSON and WriteJSONArray.
+type StreamOptions struct {
+	// Status is the success status code. Defaults to 200.
+	Status int
+	// FlushEvery flushes the response after this many items. Defaults
+	// to 100; 1 flushes every item.
+	FlushEvery int
+}
+
+// WriteNDJSON streams seq as newline-delimited JSON
+// (application/x-ndjson), one value per line, without buffering the whole
+// result. An error yielded before the first item is written as a normal
+// Problem response. An error after that ends the stream with a final
+// {"error": <problem>} line and the Stream-Error trailer. Streaming stops
+// when the client goes away; the returned error reports why the stream
+// ended early, if it did.
+func WriteNDJSON[T any](w http.ResponseWriter, r *http.Request, seq iter.Seq2[T, error], opts StreamOptions) error {
+	return stream(w, r, seq, opts, ndjson)
+}
+
+// WriteJSONArray streams seq as a single JSON array. Because a valid array
+// cannot carry an error, a failure after the first item leaves the array
+// unterminated so clients cannot mistake a partial result for a complete
+// one; the cause is reported in the Stream-Error trailer. Otherwise it
+// behaves like WriteNDJSON.
+func WriteJSONArray[T any](w http.ResponseWriter, r *http.Request, seq iter.Seq2[T, error], opts StreamOptions) error {
+	return stream(w, r, seq, opts, jsonArray)
+}
+
+// FromChan adapts a channel to the stream writers. The stream ends when
+// ch is closed, or with ctx's error when ctx is done, so a handler waiting
+// on a quiet channel still stops when the client goes away.
+func FromChan[T any](ctx context.Context, ch <-chan T) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		for {
+			select {
+			case v, ok := <-ch:
+				if !ok {
+					return
+				}
+				if !yield(v, nil) {
+					return
+				}
+			case <-ctx.Done():
+				var zero T
+				yield(zero, ctx.Err())
+				return
+			}
+		}
+	}
+}
+
+// FromSeq adapts an infallible iterator to the stream writers.
+func FromSeq[T any](seq iter.Seq[T]) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		for v := range seq {
+			if !yield(v, nil) {
+				return
+			}
+		}
+	}
+}
+
+type streamFormat struct {
+	contentType string
+	open, sep   string
+	close       string
+	errorLine   bool
+}
+
+var (
+	ndjson    = streamFormat{contentType: "application/x-ndjson", sep: "\n", close: "\n", errorLine: true}
+	jsonArray = streamFormat{contentType: "application/json", open: "[\n", sep: ",\n", close: "\n]\n"}
+)
+
+func stream[T any](w http.ResponseWriter, r *http.Request, seq iter.Seq2[T, error], opts StreamOptions, f streamFormat) error {
+	if opts.Status == 0 {
+		opts.Status = http.StatusOK
+	}
+	if opts.FlushEvery <= 0 {
+		opts.FlushEvery = 100
+	}
+	ctx := r.Context()
+	rc := http.NewResponseController(w)
+	started := false
+	n := 0
+
+	start := func() error {
+		started = true
+		h := w.Header()
+		h.Set("Content-Type", f.contentType)
+		h.Set("X-Content-Type-Options", "nosniff")
+		h.Add("Trailer", StreamErrorTrailer)
+		// A long export would otherwise be cut off by the server's
+		// WriteTimeout; the request context still bounds it.
+		if err := rc.SetWriteDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+			return err
+		}
+		w.WriteHeader(opts.Status)
+		_, err := w.Write([]byte(f.open))
+		return err
+	}
+
+	var streamErr error
+	for v, err := range seq {
+		if err := ctx.Err(); err != nil {
+			// The client went away; an error caused by that is not worth
+			// reporting to nobody.
+			return err
+		}
+		if err != nil {
+			streamErr = err
+			break
+		}
+		b, err := json.Marshal(v)
+		if err != nil {
+			streamErr = err
+			break
+		}
+		if !started {
+			if err := start(); err != nil {
+				return err
+			}
+		} else if _, err := w.Write([]byte(f.sep)); err != nil {
+			return err
+		}
+		if _, err := w.Write(b); err != nil {
+			return err
+		}
+		n++
+		if n%opts.FlushEvery == 0 {
+			if err := flush(rc); err != nil {
+				return err
+			}
+		}
+	}
+
+	if streamErr != nil {
+		if !started {
+			httpx.WriteError(w, r, streamErr)
+			return streamErr
+		}
+		p := httpx.DefaultErrorMapper.Resolve(streamErr)
+		p = httpx.Correlate(r, httpx.Localize(r, p.Status, p))
+		logctx.FromContext(ctx).Error("stream failed",
+			"status", p.Status, "items", n, "error", streamErr)
+		if f.errorLine {
+			line, _ := json.Marshal(map[string]any{"error": p})
+			_, _ = w.Write([]byte(f.sep))
+			_, _ = w.Write(line)
+			_, _ = w.Write([]byte(f.close))
+		}
+		w.Header().Set(StreamErrorTrailer, p.Detail)
+		_ = flush(rc)
+		return streamErr
+	}
+
+	if !started {
+		if err := start(); err != nil {
+			return err
+		}
+		if f.errorLine {
+			// An empty NDJSON stream has no lines at all.
+			return flush(rc)
+		}
+	}
+	if _, err := w.Write([]byte(f.close)); err != nil {
+		return err
+	}
+	return flush(rc)
+}
+
+func flush(rc *http.ResponseController) error {
+	if err := rc.Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return err
+	}
+	return nil
+}
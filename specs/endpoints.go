@@ -16,7 +16,8 @@ const (
 	DocsInfo    = "/docs/info"
 
 	// System endpoints
-	Version = "/version"
+	Version  = "/version"
+	LogLevel = "/loglevel"
 
 	// Metrics endpoint (Prometheus)
 	Metrics = "/metrics"
@@ -52,9 +53,11 @@ var DocsEndpoints = struct {
 
 // SystemEndpoints groups all system-related endpoints
 var SystemEndpoints = struct {
-	Version string
+	Version  string
+	LogLevel string
 }{
-	Version: Version,
+	Version:  Version,
+	LogLevel: LogLevel,
 }
 
 // AllEndpoints groups all available endpoints
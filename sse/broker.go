@@ -0,0 +1,296 @@
+package sse
+
+import (
+	"encoding/json"
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aatuh/api-toolkit/clock"
+	"github.com/aatuh/api-toolkit/logctx"
+	"github.com/aatuh/api-toolkit/ports"
+)
+
+// ErrClosed is returned when publishing to a closed broker.
+var ErrClosed = errors.New("sse: broker closed")
+
+// Event is a single server-sent event.
+type Event struct {
+	// ID is assigned by the broker from a broker-wide sequence, so one
+	// Last-Event-ID resumes every topic a client follows.
+	ID    string
+	Topic string
+	// Type is sent as the "event:" field; empty means "message".
+	Type string
+	// Data is sent as-is when it is a string or []byte and JSON-encoded
+	// otherwise.
+	Data any
+	// Retry, when set, tells the client how long to wait before
+	// reconnecting.
+	Retry time.Duration
+
+	seq   uint64
+	frame []byte
+}
+
+// Options configures a Broker.
+type Options struct {
+	// BufferSize is the number of recent events kept per topic for
+	// Last-Event-ID replay. Defaults to 256.
+	BufferSize int
+	// ClientBuffer is the per-subscriber queue length. Subscribers that
+	// fall this far behind are disconnected and can resume with
+	// Last-Event-ID. Defaults to 64.
+	ClientBuffer int
+	// Heartbeat is the interval of keep-alive comments. Defaults to 15s.
+	Heartbeat time.Duration
+	// MaxTopics caps the distinct topics one subscription may name.
+	// Defaults to 16.
+	MaxTopics int
+	Clock     ports.Clock
+	Log       ports.Logger
+}
+
+// Broker fans events out to subscribers by topic.
+type Broker struct {
+	opts Options
+	clk  ports.Clock
+	log  ports.Logger
+
+	mu     sync.Mutex
+	seq    uint64
+	topics map[string]*topic
+	closed bool
+}
+
+type topic struct {
+	ring []Event // oldest first once full, see next
+	next int
+	full bool
+	subs map[*subscriber]struct{}
+}
+
+type subscriber struct {
+	ch      chan Event
+	dropped chan struct{}
+	once    sync.Once
+}
+
+func (s *subscriber) drop() { s.once.Do(func() { close(s.dropped) }) }
+
+// NewBroker returns an empty broker.
+func NewBroker(opts Options) *Broker {
+	if opts.BufferSize <= 0 {
+		opts.BufferSize = 256
+	}
+	if opts.ClientBuffer <= 0 {
+		opts.ClientBuffer = 64
+	}
+	if opts.Heartbeat <= 0 {
+		opts.Heartbeat = 15 * time.Second
+	}
+	if opts.MaxTopics <= 0 {
+		opts.MaxTopics = 16
+	}
+	log := opts.Log
+	if log == nil {
+		log = logctx.Nop()
+	}
+	return &Broker{
+		opts:   opts,
+		clk:    clock.OrSystem(opts.Clock),
+		log:    log,
+		topics: map[string]*topic{},
+	}
+}
+
+// Publish sends ev to every subscriber of topicName and records it for
+// replay. It returns the event with its assigned ID. Publishing never
+// blocks on slow subscribers.
+func (b *Broker) Publish(topicName string, ev Event) (Event, error) {
+	data, err := encodeData(ev.Data)
+	if err != nil {
+		return Event{}, err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return Event{}, ErrClosed
+	}
+	b.seq++
+	ev.seq = b.seq
+	ev.ID = strconv.FormatUint(b.seq, 10)
+	ev.Topic = topicName
+	ev.frame = frame(ev, data)
+
+	t := b.topic(topicName)
+	if t.ring == nil {
+		t.ring = make([]Event, b.opts.BufferSize)
+	}
+	t.ring[t.next] = ev
+	t.next = (t.next + 1) % len(t.ring)
+	if t.next == 0 {
+		t.full = true
+	}
+	for s := range t.subs {
+		select {
+		case s.ch <- ev:
+		default:
+			b.log.Warn("sse subscriber too slow, disconnecting", "topic", topicName)
+			b.removeLocked(s)
+			s.drop()
+		}
+	}
+	return ev, nil
+}
+
+// Subscribers returns the number of subscribers of topicName.
+func (b *Broker) Subscribers(topicName string) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if t, ok := b.topics[topicName]; ok {
+		return len(t.subs)
+	}
+	return 0
+}
+
+// Close disconnects all subscribers and rejects further publishes.
+func (b *Broker) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	for _, t := range b.topics {
+		for s := range t.subs {
+			s.drop()
+		}
+		t.subs = nil
+	}
+}
+
+// subscribe registers a subscriber and returns the buffered events after
+// lastID, in publish order. Both happen under the lock so nothing is lost
+// or duplicated between replay and live delivery.
+func (b *Broker) subscribe(topics []string, lastID string) (*subscriber, []Event, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return nil, nil, ErrClosed
+	}
+	s := &subscriber{
+		ch:      make(chan Event, b.opts.ClientBuffer),
+		dropped: make(chan struct{}),
+	}
+	var replay []Event
+	after, replayOK := parseID(lastID)
+	for _, name := range topics {
+		t := b.topic(name)
+		if t.subs == nil {
+			t.subs = map[*subscriber]struct{}{}
+		}
+		t.subs[s] = struct{}{}
+		if replayOK {
+			replay = append(replay, t.since(after)...)
+		}
+	}
+	sort.Slice(replay, func(i, j int) bool { return replay[i].seq < replay[j].seq })
+	return s, replay, nil
+}
+
+func (b *Broker) unsubscribe(s *subscriber) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.removeLocked(s)
+}
+
+func (b *Broker) removeLocked(s *subscriber) {
+	for name, t := range b.topics {
+		delete(t.subs, s)
+		if len(t.subs) == 0 && t.ring == nil {
+			delete(b.topics, name)
+		}
+	}
+}
+
+func (b *Broker) topic(name string) *topic {
+	t, ok := b.topics[name]
+	if !ok {
+		t = &topic{}
+		b.topics[name] = t
+	}
+	return t
+}
+
+// since returns buffered events with a sequence above after, oldest first.
+func (t *topic) since(after uint64) []Event {
+	if t.ring == nil {
+		return nil
+	}
+	var out []Event
+	start, n := 0, t.next
+	if t.full {
+		start, n = t.next, len(t.ring)
+	}
+	for i := 0; i < n; i++ {
+		ev := t.ring[(start+i)%len(t.ring)]
+		if ev.seq > after {
+			out = append(out, ev)
+		}
+	}
+	return out
+}
+
+func parseID(id string) (uint64, bool) {
+	if id == "" {
+		return 0, false
+	}
+	n, err := strconv.ParseUint(id, 10, 64)
+	return n, err == nil
+}
+
+func encodeData(v any) (string, error) {
+	switch d := v.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return d, nil
+	case []byte:
+		return string(d), nil
+	default:
+		b, err := json.Marshal(d)
+		return string(b), err
+	}
+}
+
+// frame renders ev in the text/event-stream wire format.
+func frame(ev Event, data string) []byte {
+	var sb strings.Builder
+	sb.WriteString("id: ")
+	sb.WriteString(ev.ID)
+	sb.WriteByte('\n')
+	if ev.Type != "" {
+		sb.WriteString("event: ")
+		sb.WriteString(sanitizeLine(ev.Type))
+		sb.WriteByte('\n')
+	}
+	if ev.Retry > 0 {
+		sb.WriteString("retry: ")
+		sb.WriteString(strconv.FormatInt(ev.Retry.Milliseconds(), 10))
+		sb.WriteByte('\n')
+	}
+	data = strings.ReplaceAll(data, "\r\n", "\n")
+	for _, line := range strings.Split(data, "\n") {
+		sb.WriteString("data: ")
+		sb.WriteString(strings.TrimSuffix(line, "\r"))
+		sb.WriteByte('\n')
+	}
+	sb.WriteByte('\n')
+	return []byte(sb.String())
+}
+
+func sanitizeLine(s string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(s)
+}
@@ -0,0 +1,129 @@
+package sse
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/aatuh/api-toolkit/httpx"
+	"github.com/aatuh/api-toolkit/logctx"
+)
+
+// ContentType is the media type of event streams.
+const ContentType = "text/event-stream"
+
+// TopicsFunc selects the topics a request subscribes to. Returning an
+// error rejects the subscription; it is written through httpx.WriteError,
+// so ProblemError values (e.g. a 403) are honoured.
+type TopicsFunc func(r *http.Request) ([]string, error)
+
+// QueryTopics subscribes to the repeated "topic" query parameter.
+func QueryTopics(r *http.Request) ([]string, error) {
+	return r.URL.Query()["topic"], nil
+}
+
+// Handler streams events of the selected topics (QueryTopics when nil).
+// It replays buffered events after the Last-Event-ID header (or the
+// lastEventId query parameter used by EventSource polyfills), sends a
+// heartbeat comment when idle and unsubscribes when the client leaves.
+// Mount it outside buffering middleware and exempt its route from
+// middleware/timeout, e.g. with a negative timeout.Routes budget. With
+// bootstrap.NewDefaultRouter, pass bootstrap.WithTimeoutRoutes with a
+// negative budget for the stream's path.
+func (b *Broker) Handler(topics TopicsFunc) http.Handler {
+	if topics == nil {
+		topics = QueryTopics
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		names, err := topics(r)
+		if err != nil {
+			httpx.WriteError(w, r, err)
+			return
+		}
+		names = dedupe(names)
+		if len(names) == 0 {
+			httpx.WriteProblemFor(w, r, http.StatusBadRequest, httpx.Problem{
+				Detail: "at least one topic is required",
+			})
+			return
+		}
+		if len(names) > b.opts.MaxTopics {
+			httpx.WriteProblemFor(w, r, http.StatusBadRequest, httpx.Problem{
+				Detail: fmt.Sprintf("at most %d topics may be subscribed at once", b.opts.MaxTopics),
+			})
+			return
+		}
+		lastID := r.Header.Get("Last-Event-ID")
+		if lastID == "" {
+			lastID = r.URL.Query().Get("lastEventId")
+		}
+		sub, replay, err := b.subscribe(names, lastID)
+		if errors.Is(err, ErrClosed) {
+			httpx.WriteProblemFor(w, r, http.StatusServiceUnavailable, httpx.Problem{
+				Detail: "event stream is shutting down",
+			})
+			return
+		}
+		defer b.unsubscribe(sub)
+
+		rc := http.NewResponseController(w)
+		// Streams outlive the server's WriteTimeout, which would
+		// otherwise close them.
+		if err := rc.SetWriteDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+			return
+		}
+		h := w.Header()
+		h.Set("Content-Type", ContentType)
+		h.Set("Cache-Control", "no-cache")
+		h.Set("X-Accel-Buffering", "no")
+		w.WriteHeader(http.StatusOK)
+		for _, ev := range replay {
+			if _, err := w.Write(ev.frame); err != nil {
+				return
+			}
+		}
+		if err := rc.Flush(); err != nil {
+			logctx.FromContext(r.Context()).Error("sse requires a flushable response writer",
+				"error", err)
+			return
+		}
+
+		heartbeat := b.clk.NewTicker(b.opts.Heartbeat)
+		defer heartbeat.Stop()
+		ctx := r.Context()
+		for {
+			var chunk []byte
+			select {
+			case <-ctx.Done():
+				return
+			case <-sub.dropped:
+				return
+			case ev := <-sub.ch:
+				chunk = ev.frame
+			case <-heartbeat.C():
+				chunk = []byte(": ping\n\n")
+			}
+			if _, err := w.Write(chunk); err != nil {
+				return
+			}
+			if err := rc.Flush(); err != nil {
+				return
+			}
+		}
+	})
+}
+
+// dedupe drops repeated topics, keeping the first occurrence, so a topic
+// named twice is neither replayed twice nor counted twice.
+func dedupe(names []string) []string {
+	seen := make(map[string]bool, len(names))
+	out := names[:0:0]
+	for _, n := range names {
+		if !seen[n] {
+			seen[n] = true
+			out = append(out, n)
+		}
+	}
+	return out
+}
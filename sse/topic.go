@@ -0,0 +1,23 @@
+package sse
+
+// Topic publishes events of one payload type to a named topic.
+type Topic[T any] struct {
+	b         *Broker
+	name      string
+	eventType string
+}
+
+// NewTopic returns a typed publisher for name. eventType is sent as the
+// "event:" field of every event; empty means "message".
+func NewTopic[T any](b *Broker, name, eventType string) Topic[T] {
+	return Topic[T]{b: b, name: name, eventType: eventType}
+}
+
+// Name returns the topic name.
+func (t Topic[T]) Name() string { return t.name }
+
+// Publish sends data to the topic's subscribers and returns the event ID.
+func (t Topic[T]) Publish(data T) (string, error) {
+	ev, err := t.b.Publish(t.name, Event{Type: t.eventType, Data: data})
+	return ev.ID, err
+}
@@ -4,6 +4,8 @@ package txpostgres
 import (
 	"context"
 	"errors"
+	"strconv"
+	"time"
 
 	"github.com/aatuh/api-toolkit/ports"
 	"github.com/jackc/pgx/v5"
@@ -24,6 +26,9 @@ var txKey txKeyType
 // Manager implements ports.TxManager using a pgx-like pool.
 type Manager struct {
 	Pool ports.DatabasePool
+	// NoStatementTimeout skips deriving statement_timeout from the
+	// context deadline (see StatementTimeout).
+	NoStatementTimeout bool
 }
 
 func New(pool ports.DatabasePool) *Manager { return &Manager{Pool: pool} }
@@ -43,6 +48,17 @@ func (m *Manager) WithinTx(
 	}
 	defer func() { _ = tx.Rollback(ctx) }()
 
+	if !m.NoStatementTimeout {
+		if d, ok := StatementTimeout(ctx); ok {
+			// Transaction-local, so it resets when the connection is
+			// returned to the pool.
+			if _, err := tx.Exec(ctx, `SELECT set_config('statement_timeout', $1, true)`,
+				strconv.FormatInt(d.Milliseconds(), 10)); err != nil {
+				return err
+			}
+		}
+	}
+
 	txCtx := context.WithValue(ctx, txKey, tx)
 	if err := fn(txCtx); err != nil {
 		return err
@@ -50,6 +66,21 @@ func (m *Manager) WithinTx(
 	return tx.Commit(ctx)
 }
 
+// StatementTimeout returns the time left before the context deadline,
+// rounded up to whole milliseconds, for use as a Postgres
+// statement_timeout. WithinTx applies it, so statements are cancelled by
+// the server too when a request's time budget runs out.
+func StatementTimeout(ctx context.Context) (time.Duration, bool) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return 0, false
+	}
+	// At least 1ms: zero would disable the timeout, and the context is
+	// about to expire anyway.
+	d := max(time.Until(deadline), time.Millisecond)
+	return (d + time.Millisecond - 1).Truncate(time.Millisecond), true
+}
+
 // FromCtx returns the active transaction if present; otherwise a
 // facade that acquires/releases a connection per call (no leaks).
 func FromCtx(ctx context.Context, pool ports.DatabasePool) DBer {
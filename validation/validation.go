@@ -6,6 +6,7 @@ import (
 	"reflect"
 	"strings"
 
+	"github.com/aatuh/api-toolkit/i18n"
 	"github.com/aatuh/api-toolkit/ports"
 	"github.com/go-playground/validator/v10"
 )
@@ -79,10 +80,10 @@ func NewPlaygroundValidator() ports.Validator {
 
 func (p *playgroundValidator) Validate(ctx context.Context, value interface{}) error {
 	if value == nil {
-		return ValidationError{Message: "value is required"}
+		return ValidationError{Message: i18n.T(ctx, "validation.value_required")}
 	}
 	if isStruct(value) {
-		return convertError(p.validator.StructCtx(ctx, value))
+		return convertError(ctx, p.validator.StructCtx(ctx, value))
 	}
 	// Non-struct validations are not supported via generic Validate; consider using ValidateField.
 	return nil
@@ -90,19 +91,19 @@ func (p *playgroundValidator) Validate(ctx context.Context, value interface{}) e
 
 func (p *playgroundValidator) ValidateStruct(ctx context.Context, obj interface{}) error {
 	if obj == nil {
-		return ValidationError{Message: "object is required"}
+		return ValidationError{Message: i18n.T(ctx, "validation.object_required")}
 	}
-	return convertError(p.validator.StructCtx(ctx, obj))
+	return convertError(ctx, p.validator.StructCtx(ctx, obj))
 }
 
 func (p *playgroundValidator) ValidateField(ctx context.Context, obj interface{}, field string) error {
 	if obj == nil {
-		return ValidationError{Message: "object is required"}
+		return ValidationError{Message: i18n.T(ctx, "validation.object_required")}
 	}
 	if strings.TrimSpace(field) == "" {
-		return ValidationError{Message: "field name is required"}
+		return ValidationError{Message: i18n.T(ctx, "validation.field_name_required")}
 	}
-	return convertError(p.validator.StructPartialCtx(ctx, obj, field))
+	return convertError(ctx, p.validator.StructPartialCtx(ctx, obj, field))
 }
 
 func isStruct(v interface{}) bool {
@@ -116,14 +117,14 @@ func isStruct(v interface{}) bool {
 	return rv.IsValid() && rv.Kind() == reflect.Struct
 }
 
-func convertError(err error) error {
+func convertError(ctx context.Context, err error) error {
 	if err == nil {
 		return nil
 	}
 	if ve, ok := err.(validator.ValidationErrors); ok {
 		errs := ValidationErrors{}
 		for _, fe := range ve {
-			msg := buildMessage(fe)
+			msg := buildMessage(ctx, fe)
 			errs.Errors = append(errs.Errors, ValidationError{
 				Field:   fe.Field(),
 				Message: msg,
@@ -138,22 +139,16 @@ func convertError(err error) error {
 	return err
 }
 
-func buildMessage(fe validator.FieldError) string {
-	switch fe.Tag() {
-	case "required":
-		return "is required"
-	case "min":
-		return fmt.Sprintf("must be at least %s", fe.Param())
-	case "max":
-		return fmt.Sprintf("must be at most %s", fe.Param())
-	case "len":
-		return fmt.Sprintf("must be %s in length", fe.Param())
-	case "oneof":
-		return fmt.Sprintf("must be one of [%s]", fe.Param())
-	default:
+// buildMessage localizes fe into the language carried by ctx (see
+// i18n.Middleware). Tags with a "validation.<tag>" message use it; others
+// get a generic message.
+func buildMessage(ctx context.Context, fe validator.FieldError) string {
+	id := "validation." + fe.Tag()
+	if _, ok := i18n.Default.Lookup("", id); !ok {
+		id = "validation.failed"
 		if fe.Param() != "" {
-			return fmt.Sprintf("failed '%s'=%s validation", fe.Tag(), fe.Param())
+			id = "validation.failed_param"
 		}
-		return fmt.Sprintf("failed '%s' validation", fe.Tag())
 	}
+	return i18n.T(ctx, id, "tag", fe.Tag(), "param", fe.Param())
 }